@@ -0,0 +1,13 @@
+//4x4 ordered dither thresholds
+const int bayer[16] = int[16](
+    0, 8, 2, 10,
+    12, 4, 14, 6,
+    3, 11, 1, 9,
+    15, 7, 13, 5
+);
+
+//returns the dither level of the pixel from 0 to 15, the fades draw the levels under the threshold
+int ditherLevel() {
+    ivec2 p = ivec2(gl_FragCoord.xy) % 4;
+    return bayer[p.y * 4 + p.x];
+}
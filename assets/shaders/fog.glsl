@@ -0,0 +1,6 @@
+//fades the colour of the fragment at the world position pos to the horizon colour with the distance,
+//needs per_frame.glsl
+vec3 fog(vec3 color, vec3 pos) {
+    float d = length(pos.xz - cameraPos.xz);
+    return mix(color, horizonColor, smoothstep(fogStart, fogEnd, d));
+}
@@ -1,7 +1,42 @@
 #version 330
+#include "per_frame.glsl"
+#include "fog.glsl"
+#include "light.glsl"
+#include "shadow.glsl"
+#include "dither.glsl"
 uniform sampler2D tex;
-in vec2 fragTexCoord;
-out vec4 outputColor;
+//the texels with lower alpha are discarded by the cutout pass
+uniform float alphaTest;
+//the dither levels drawn by the chunks of the detail tiles around the player, red from and green to,
+//the texel 0, 0 is the tile at chunkFadeOrigin, the tiles are DETAIL_TILE_SIZE blocks wide
+uniform sampler2D chunkFade;
+uniform ivec2 chunkFadeOrigin;
+in vec2 fragUV;
+in vec3 fragPos;
+out vec4 color;
+
+//reports whether the chunks draw the pixel at the world position while they fade between the terrain tiles
+bool chunkDrawn(vec3 pos) {
+    ivec2 tile = ivec2(floor(pos.xz / float(DETAIL_TILE_SIZE))) - chunkFadeOrigin;
+    ivec2 levels = ivec2(texelFetch(chunkFade, tile, 0).rg * 255.0 + 0.5);
+    int level = ditherLevel();
+    return level >= levels.x && level < levels.y;
+}
+
 void main() {
-    outputColor = texture(tex, fragTexCoord);
+    //the derivatives are taken before any fragment is discarded
+    vec3 normal = faceNormal(fragPos);
+    //the faces on the border of the tiles are moved inside their block, the normal points to the camera
+    if (!chunkDrawn(fragPos + (gl_FrontFacing ? -0.01 : 0.01) * normal)) {
+        discard;
+    }
+    color = texture(tex, fragUV);
+    if (color.a < alphaTest) {
+        discard;
+    }
+    float sun = sunLight(normal);
+    if (sun > 0.0) {
+        sun *= shadow(fragPos, normal);
+    }
+    color.rgb = fog(color.rgb * light(sun), fragPos);
 }
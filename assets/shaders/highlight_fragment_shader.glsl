@@ -0,0 +1,6 @@
+#version 330
+uniform vec4 color;
+out vec4 outColor;
+void main() {
+    outColor = color;
+}
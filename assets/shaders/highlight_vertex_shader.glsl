@@ -0,0 +1,7 @@
+#version 330
+#include "per_frame.glsl"
+uniform mat4 model;
+in vec3 vert;
+void main() {
+    gl_Position = projView * model * vec4(vert, 1);
+}
@@ -0,0 +1,15 @@
+//returns the normal of the flat shaded face at the world position
+vec3 faceNormal(vec3 pos) {
+    return normalize(cross(dFdx(pos), dFdy(pos)));
+}
+
+//returns the direct light of the sun on the face with the normal, it fades out at the sunset,
+//needs per_frame.glsl
+float sunLight(vec3 normal) {
+    return max(dot(normal, sunDir), 0.0) * clamp(sunDir.y * 5.0, 0.0, 1.0);
+}
+
+//returns the light of the fragment lit by the ambient light and the sun with the visibility 0 to 1
+float light(float sun) {
+    return ambient * (0.6 + 0.4 * sun);
+}
@@ -0,0 +1,24 @@
+#version 330
+#include "per_frame.glsl"
+#include "fog.glsl"
+#include "light.glsl"
+#include "dither.glsl"
+uniform sampler2D tex;
+//0 to 1 fading in, -1 to 0 fading out
+uniform float fade;
+in vec2 fragUV;
+in vec3 fragPos;
+out vec4 color;
+
+void main() {
+    //the derivatives are taken before any fragment is discarded
+    vec3 normal = faceNormal(fragPos);
+    float t = float(ditherLevel()) / 16.0;
+    //the fading out tile keeps the pixels the fading in one doesn't draw, ditherLevels in lod.go
+    //computes the same levels for the chunks
+    if (fade >= 0.0 ? t >= fade : t < 1.0 + fade) {
+        discard;
+    }
+    color = texture(tex, fragUV);
+    color.rgb = fog(color.rgb * light(sunLight(normal)), fragPos);
+}
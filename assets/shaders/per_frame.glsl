@@ -0,0 +1,12 @@
+//data uploaded once per frame, the perFrame struct in frame.go
+layout(std140) uniform PerFrame {
+    mat4 projView;
+    vec3 cameraPos;
+    float time;
+    vec3 sunDir;
+    float ambient;
+    vec3 horizonColor;
+    float fogStart;
+    vec3 zenithColor;
+    float fogEnd;
+};
@@ -0,0 +1,10 @@
+#version 330
+//adds the blurred bright parts to the input of the bloom pass
+uniform sampler2D source;
+uniform sampler2D passInput;
+uniform float intensity;
+in vec2 fragUV;
+out vec4 color;
+void main() {
+    color = vec4(texture(passInput, fragUV).rgb + texture(source, fragUV).rgb * intensity, 1);
+}
@@ -0,0 +1,11 @@
+#version 330
+//keeps the part of the colour brighter than the threshold
+uniform sampler2D source;
+uniform float threshold;
+in vec2 fragUV;
+out vec4 color;
+void main() {
+    vec3 c = texture(source, fragUV).rgb;
+    float brightness = max(c.r, max(c.g, c.b));
+    color = vec4(c * max(brightness - threshold, 0.0) / max(brightness, 0.0001), 1);
+}
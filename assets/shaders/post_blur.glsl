@@ -0,0 +1,19 @@
+#version 330
+//9 tap gaussian blur in the direction dirX, dirY
+uniform sampler2D source;
+uniform vec2 texelSize;
+uniform float dirX;
+uniform float dirY;
+uniform float radius;
+in vec2 fragUV;
+out vec4 color;
+const float weights[5] = float[5](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+void main() {
+    vec2 offset = vec2(dirX, dirY) * texelSize * radius;
+    vec3 sum = texture(source, fragUV).rgb * weights[0];
+    for (int i = 1; i < 5; i++) {
+        sum += texture(source, fragUV + offset * float(i)).rgb * weights[i];
+        sum += texture(source, fragUV - offset * float(i)).rgb * weights[i];
+    }
+    color = vec4(sum, 1);
+}
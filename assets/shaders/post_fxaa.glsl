@@ -0,0 +1,37 @@
+#version 330
+//fast approximate anti-aliasing blurring the edges along their direction
+uniform sampler2D source;
+uniform vec2 texelSize;
+in vec2 fragUV;
+out vec4 color;
+
+const float reduceMin = 1.0 / 128.0;
+const float reduceMul = 1.0 / 8.0;
+const float spanMax = 8.0;
+
+float luma(vec3 c) {
+    return dot(c, vec3(0.299, 0.587, 0.114));
+}
+
+void main() {
+    vec3 rgbM = texture(source, fragUV).rgb;
+    float lumaNW = luma(texture(source, fragUV + vec2(-1, -1) * texelSize).rgb);
+    float lumaNE = luma(texture(source, fragUV + vec2(1, -1) * texelSize).rgb);
+    float lumaSW = luma(texture(source, fragUV + vec2(-1, 1) * texelSize).rgb);
+    float lumaSE = luma(texture(source, fragUV + vec2(1, 1) * texelSize).rgb);
+    float lumaM = luma(rgbM);
+    float lumaMin = min(lumaM, min(min(lumaNW, lumaNE), min(lumaSW, lumaSE)));
+    float lumaMax = max(lumaM, max(max(lumaNW, lumaNE), max(lumaSW, lumaSE)));
+
+    vec2 dir = vec2(-((lumaNW + lumaNE) - (lumaSW + lumaSE)), (lumaNW + lumaSW) - (lumaNE + lumaSE));
+    float dirReduce = max((lumaNW + lumaNE + lumaSW + lumaSE) * 0.25 * reduceMul, reduceMin);
+    float rcpDirMin = 1.0 / (min(abs(dir.x), abs(dir.y)) + dirReduce);
+    dir = clamp(dir * rcpDirMin, -spanMax, spanMax) * texelSize;
+
+    vec3 rgbA = 0.5 * (texture(source, fragUV + dir * (1.0 / 3.0 - 0.5)).rgb +
+        texture(source, fragUV + dir * (2.0 / 3.0 - 0.5)).rgb);
+    vec3 rgbB = rgbA * 0.5 + 0.25 * (texture(source, fragUV - dir * 0.5).rgb +
+        texture(source, fragUV + dir * 0.5).rgb);
+    float lumaB = luma(rgbB);
+    color = vec4(lumaB < lumaMin || lumaB > lumaMax ? rgbA : rgbB, 1);
+}
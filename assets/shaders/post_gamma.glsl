@@ -0,0 +1,9 @@
+#version 330
+//encodes the linear colour for the display
+uniform sampler2D source;
+uniform float gamma;
+in vec2 fragUV;
+out vec4 color;
+void main() {
+    color = vec4(pow(texture(source, fragUV).rgb, vec3(1.0 / gamma)), 1);
+}
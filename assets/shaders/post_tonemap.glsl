@@ -0,0 +1,10 @@
+#version 330
+//maps the high dynamic range colour to 0-1 by the fitted ACES curve
+uniform sampler2D source;
+uniform float exposure;
+in vec2 fragUV;
+out vec4 color;
+void main() {
+    vec3 x = texture(source, fragUV).rgb * exposure;
+    color = vec4(clamp(x * (2.51 * x + 0.03) / (x * (2.43 * x + 0.59) + 0.14), 0.0, 1.0), 1);
+}
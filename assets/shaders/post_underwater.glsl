@@ -0,0 +1,12 @@
+#version 330
+//tints and darkens the view when the camera is under water, the pass is skipped above the water
+uniform sampler2D source;
+uniform float strength;
+in vec2 fragUV;
+out vec4 color;
+const vec3 tint = vec3(0.1, 0.35, 0.6);
+void main() {
+    vec3 c = texture(source, fragUV).rgb;
+    float edge = 1.0 - 0.3 * dot(fragUV - 0.5, fragUV - 0.5);
+    color = vec4(mix(c, c * tint * 1.5 * edge, strength), 1);
+}
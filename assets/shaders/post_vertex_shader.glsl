@@ -0,0 +1,8 @@
+#version 330
+//full screen triangle of the post-processing steps, drawn without vertex buffers
+out vec2 fragUV;
+void main() {
+    vec2 p = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+    fragUV = p;
+    gl_Position = vec4(p * 2.0 - 1.0, 0, 1);
+}
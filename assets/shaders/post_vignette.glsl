@@ -0,0 +1,12 @@
+#version 330
+//darkens the corners of the screen outside of the radius
+uniform sampler2D source;
+uniform float strength;
+uniform float radius;
+in vec2 fragUV;
+out vec4 color;
+void main() {
+    vec3 c = texture(source, fragUV).rgb;
+    float d = length(fragUV - 0.5) * 1.41421;
+    color = vec4(c * (1.0 - strength * smoothstep(radius, 1.0, d)), 1);
+}
@@ -0,0 +1,37 @@
+//cascaded shadow map of the sun, the shadowData struct in shadow.go,
+//needs per_frame.glsl, enabled by the SHADOWS define
+#ifdef SHADOWS
+layout(std140) uniform Shadows {
+    mat4 shadowMatrices[4];
+    vec4 cascadeSplits;
+    vec4 shadowBias;
+    vec3 viewDir;
+};
+uniform sampler2DArrayShadow shadowMap;
+
+//returns the visibility of the sun at the world position, 0 in the shadow and 1 in the light
+float shadow(vec3 pos, vec3 normal) {
+    float depth = dot(pos - cameraPos, viewDir);
+    int cascade = 0;
+    while (cascade < SHADOW_CASCADES && depth >= cascadeSplits[cascade]) {
+        cascade++;
+    }
+    if (cascade == SHADOW_CASCADES) {
+        return 1.0;
+    }
+    //the position is moved along the normal by the texel against the shadow acne
+    vec4 p = shadowMatrices[cascade] * vec4(pos + normal * shadowBias[cascade], 1);
+    vec2 texel = 1.0 / vec2(textureSize(shadowMap, 0).xy);
+    float sum = 0.0;
+    for (int x = -SHADOW_PCF; x <= SHADOW_PCF; x++) {
+        for (int y = -SHADOW_PCF; y <= SHADOW_PCF; y++) {
+            sum += texture(shadowMap, vec4(p.xy + vec2(x, y) * texel, cascade, p.z));
+        }
+    }
+    return sum / float((2 * SHADOW_PCF + 1) * (2 * SHADOW_PCF + 1));
+}
+#else
+float shadow(vec3 pos, vec3 normal) {
+    return 1.0;
+}
+#endif
@@ -0,0 +1,10 @@
+#version 330
+uniform sampler2D tex;
+//the transparent texels of the cutout blocks don't cast the shadows
+uniform float alphaTest;
+in vec2 fragUV;
+void main() {
+    if (alphaTest > 0.0 && texture(tex, fragUV).a < alphaTest) {
+        discard;
+    }
+}
@@ -0,0 +1,10 @@
+#version 330
+//depth of the chunks seen from the sun, it draws the vertex arrays of the chunk program
+uniform mat4 lightProjView;
+layout(location = 0) in vec3 vert;
+layout(location = 1) in vec2 uv;
+out vec2 fragUV;
+void main() {
+    fragUV = uv;
+    gl_Position = lightProjView * vec4(vert, 1);
+}
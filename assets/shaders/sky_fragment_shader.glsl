@@ -0,0 +1,28 @@
+#version 330
+#include "per_frame.glsl"
+in vec3 fragDir;
+out vec4 color;
+
+float hash(vec3 p) {
+    p = fract(p * 0.3183099 + 0.1);
+    p *= 17.0;
+    return fract(p.x * p.y * p.z * (p.x + p.y + p.z));
+}
+
+void main() {
+    vec3 dir = normalize(fragDir);
+    float h = max(dir.y, 0.0);
+    vec3 sky = mix(horizonColor, zenithColor, pow(h, 0.5));
+
+    //the stars are the cells of the direction grid, they fade in as the sun sets
+    float night = clamp(-sunDir.y * 4.0, 0.0, 1.0);
+    vec3 cell = floor(dir * 200.0);
+    float star = step(0.998, hash(cell)) * night * smoothstep(0.0, 0.1, dir.y);
+    sky += vec3(star);
+
+    float sun = dot(dir, sunDir);
+    sky += vec3(1.0, 0.9, 0.7) * (smoothstep(0.9995, 0.9997, sun) + pow(max(sun, 0.0), 64.0) * 0.3);
+    float moon = dot(dir, -sunDir);
+    sky += vec3(0.8, 0.85, 0.9) * smoothstep(0.9997, 0.9998, moon);
+    color = vec4(sky, 1);
+}
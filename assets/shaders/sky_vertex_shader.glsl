@@ -0,0 +1,12 @@
+#version 330
+uniform mat4 invProjView;
+//direction of the view ray of the vertex
+out vec3 fragDir;
+void main() {
+    //the fullscreen triangle at the far plane
+    vec2 p = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2) * 2.0 - 1.0;
+    vec4 far = invProjView * vec4(p, 1, 1);
+    vec4 near = invProjView * vec4(p, -1, 1);
+    fragDir = far.xyz / far.w - near.xyz / near.w;
+    gl_Position = vec4(p, 1, 1);
+}
@@ -1,11 +1,12 @@
 #version 330
-uniform mat4 projection;
-uniform mat4 camera;
-uniform mat4 model;
-in vec3 vert;
-in vec2 vertTexCoord;
-out vec2 fragTexCoord;
+#include "per_frame.glsl"
+//the locations are fixed so the shadow pass can draw the same vertex arrays
+layout(location = 0) in vec3 vert;
+layout(location = 1) in vec2 uv;
+out vec2 fragUV;
+out vec3 fragPos;
 void main() {
-    fragTexCoord = vertTexCoord;
-    gl_Position = projection * camera * model * vec4(vert, 1);
+    fragUV = uv;
+    fragPos = vert;
+    gl_Position = projView * vec4(vert, 1);
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/microo8/craft/glw"
+)
+
+//bindingsPath is the config file overriding the default bindings
+const bindingsPath = "assets/config/bindings.json"
+
+var defaultBindings = map[string][]glw.Binding{
+	"forward": {glw.KeyBinding(glfw.KeyW)},
+	"back":    {glw.KeyBinding(glfw.KeyS)},
+	"left":    {glw.KeyBinding(glfw.KeyA)},
+	"right":   {glw.KeyBinding(glfw.KeyD)},
+	"jump":    {glw.KeyBinding(glfw.KeySpace)},
+	"down":    {glw.KeyBinding(glfw.KeyLeftShift)},
+	"fly":     {glw.KeyBinding(glfw.KeyF)},
+	"break":   {glw.MouseBinding(glfw.MouseButtonLeft)},
+	"place":   {glw.MouseBinding(glfw.MouseButtonRight)},
+	"slot1":   {glw.KeyBinding(glfw.Key1)},
+	"slot2":   {glw.KeyBinding(glfw.Key2)},
+	"slot3":   {glw.KeyBinding(glfw.Key3)},
+	"slot4":   {glw.KeyBinding(glfw.Key4)},
+	"slot5":   {glw.KeyBinding(glfw.Key5)},
+	"slot6":   {glw.KeyBinding(glfw.Key6)},
+	"slot7":   {glw.KeyBinding(glfw.Key7)},
+	"slot8":   {glw.KeyBinding(glfw.Key8)},
+	"slot9":   {glw.KeyBinding(glfw.Key9)},
+
+	"fullscreen": {glw.KeyBinding(glfw.KeyF11)},
+
+	glw.ScreenshotAction:      {glw.KeyBinding(glfw.KeyF2)},
+	glw.ScreenshotHiResAction: {glw.KeyBinding(glfw.KeyF4)},
+	glw.CaptureAction:         {glw.KeyBinding(glfw.KeyF3)},
+}
+
+//appActions handles the actions controlling the app itself
+func appActions(app *glw.App) glw.UpdaterFunc {
+	return func(dt float64) {
+		if app.Input.JustPressed("fullscreen") {
+			if err := app.ToggleFullscreen(); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+//renderPass is the pass drawing the faces of the block
+type renderPass int
+
+const (
+	//opaquePass draws the blocks hiding what's behind them
+	opaquePass renderPass = iota
+	//cutoutPass draws the blocks with the transparent texels discarded and both sides of the faces
+	cutoutPass
+	//translucentPass blends the blocks over the others, the sections are sorted back to front
+	translucentPass
+	passCount
+)
+
+//cutoutAlphaTest is the alpha of the texels under which the cutout pass discards them
+const cutoutAlphaTest = 0.5
+
+//blockShape is the mesh of the block
+type blockShape int
+
+const (
+	cubeShape blockShape = iota
+	//crossShape are the two diagonal quads of the plants
+	crossShape
+)
+
+//blockInfo describes the block type in the blocks registry
+type blockInfo struct {
+	name  string
+	pass  renderPass
+	shape blockShape
+	//solid blocks collide with the player
+	solid bool
+	//liquid blocks can't be targeted and are replaced by the placed blocks
+	liquid bool
+	//cullSame hides the faces between the blocks of the same type, e.g. the water surface is drawn only at the air
+	cullSame bool
+	//texture is the column of the block in the texture atlas
+	texture int
+}
+
+//blocks is the registry of the block types indexed by the itemType
+var blocks = [...]blockInfo{
+	EmptyItem:        {name: "empty"},
+	DirtItem:         {name: "dirt", pass: opaquePass, solid: true, cullSame: true, texture: 0},
+	SandItem:         {name: "sand", pass: opaquePass, solid: true, cullSame: true, texture: 1},
+	StoneItem:        {name: "stone", pass: opaquePass, solid: true, cullSame: true, texture: 2},
+	BrickItem:        {name: "brick", pass: opaquePass, solid: true, cullSame: true, texture: 3},
+	LeavesItem:       {name: "leaves", pass: cutoutPass, solid: true, texture: 4},
+	GlassItem:        {name: "glass", pass: cutoutPass, solid: true, cullSame: true, texture: 5},
+	WaterItem:        {name: "water", pass: translucentPass, liquid: true, cullSame: true, texture: 6},
+	StainedGlassItem: {name: "stained glass", pass: translucentPass, solid: true, cullSame: true, texture: 7},
+	FlowerItem:       {name: "flower", pass: cutoutPass, shape: crossShape, texture: 8},
+}
+
+//opaque reports whether the block hides what's behind it
+func opaque(t itemType) bool {
+	return t != EmptyItem && blocks[t].pass == opaquePass
+}
+
+//faceVisible reports whether the face of the block of type t touching the neighbour of type n is drawn
+func faceVisible(t, n itemType) bool {
+	return !opaque(n) && !(n == t && blocks[t].cullSame)
+}
+
+//replaceable reports whether the placed block can take the place of the block
+func replaceable(t itemType) bool {
+	return t == EmptyItem || blocks[t].liquid
+}
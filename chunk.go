@@ -1,7 +1,10 @@
 package main
 
 import (
+	"log"
+
 	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
 	"github.com/microo8/craft/glw"
 	"github.com/microo8/craft/noise"
 )
@@ -10,8 +13,20 @@ const (
 	chunkSize         = 16
 	chunkRenderRadius = 8
 	chunkDeleteRadius = 12
+	sectionHeight     = 16
+	chunkSections     = 256 / sectionHeight
+	//seaLevel is the height of the water surface
+	seaLevel = 12
+
+	//arenaPageVertices is the size of the buffers of the chunk meshes, 20MB
+	arenaPageVertices = 1 << 20
+	//arenaMaxFragments is the number of the free ranges in the mesh arena which starts the defragmentation
+	arenaMaxFragments = 256
 )
 
+//chunkNeighbours are the offsets of the chunks sharing the side faces with the chunk
+var chunkNeighbours = [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+
 func abs(a int) int {
 	if a < 0 {
 		return -a
@@ -19,103 +34,167 @@ func abs(a int) int {
 	return a
 }
 
-func round(val float32) int {
-	if val < 0 {
-		return int(val - 0.5)
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
 	}
-	return int(val + 0.5)
+	return a / b
 }
 
+//chunkLayout is the interleaved vertex of the chunk mesh, position and texture coordinates
+var chunkLayout = glw.NewVertexLayout(
+	glw.VertexAttribute{Name: "vert", Size: 3, Type: gl.FLOAT},
+	glw.VertexAttribute{Name: "uv", Size: 2, Type: gl.FLOAT},
+)
+
+//chunkVertexSize is the number of floats of the chunk vertex
+const chunkVertexSize = 5
+
 //Chunk ...
 type Chunk struct {
-	P            int
-	Q            int
-	vertexBuffer glw.Buffer
-	uvBuffer     glw.Buffer
-	faces        int
+	P int
+	Q int
+	w *world
+	//sections are the meshes of the render passes of the 16x16x16 parts of the chunk culled separately,
+	//nil if the pass has no faces in the section
+	sections [chunkSections][passCount]*glw.ArenaMesh
+	//visibility are the faces of the sections connected through the non-opaque blocks
+	visibility [chunkSections]sectionVisibility
 
 	m [chunkSize][256][chunkSize]*Block
 }
 
-//NewChunk creates new chunk
-func NewChunk(p, q int) *Chunk {
-	chunk := &Chunk{P: p, Q: q}
+//terrainHeight returns the number of the blocks of the generated terrain column and their type,
+//the columns under the sea level are flooded by the water
+func terrainHeight(x, z int) (int, itemType) {
+	f := noise.Simplex2(float64(x)*0.01, float64(z)*0.01, 4, 0.5, 2)
+	g := noise.Simplex2(float64(x)*0.01, float64(z)*0.01, 2, 0.9, 2)
+	mh := g*32 + 16
+	h := f * mh
+	w := DirtItem
+	if h < seaLevel {
+		w = SandItem
+		if h < seaLevel-4 {
+			h = seaLevel - 4
+		}
+	}
+	return int(h), w
+}
+
+//NewChunk creates new chunk with the generated terrain, it's meshed by genBuffers
+//when its neighbours are created, so the faces on its border see their blocks
+func NewChunk(w *world, p, q int) *Chunk {
+	chunk := &Chunk{P: p, Q: q, w: w}
 	for dx := 0; dx < chunkSize; dx++ {
 		for dz := 0; dz < chunkSize; dz++ {
-			x := chunk.P*chunkSize + dx
-			z := chunk.Q*chunkSize + dz
-			f := noise.Simplex2(float64(x)*0.01, float64(z)*0.01, 4, 0.5, 2)
-			g := noise.Simplex2(float64(x)*0.01, float64(z)*0.01, 2, 0.9, 2)
-			mh := g*32 + 16
-			h := f * mh
-			w := DirtItem
-			t := 12
-			if h < float64(t) {
-				h = float64(t - 1)
-				w = SandItem
-			}
+			h, t := terrainHeight(chunk.P*chunkSize+dx, chunk.Q*chunkSize+dz)
 			for y := 0; y < 256; y++ {
-				if y < int(h) {
-					chunk.m[dx][y][dz] = &Block{t: w}
-				} else {
+				switch {
+				case y < h:
+					chunk.m[dx][y][dz] = &Block{t: t}
+				case y < seaLevel:
+					chunk.m[dx][y][dz] = &Block{t: WaterItem}
+				default:
 					chunk.m[dx][y][dz] = &Block{t: EmptyItem}
 				}
 			}
 		}
 	}
-	chunk.genBuffers()
 	return chunk
 }
 
+//genBuffers (re)builds the meshes of the chunk sections in the mesh arena of the world
 func (chunk *Chunk) genBuffers() {
-	chunk.faces = 0
+	var faces [chunkSections][passCount]int
 	for i := 0; i < chunkSize; i++ {
 		for j := 0; j < 256; j++ {
 			for k := 0; k < chunkSize; k++ {
-				chunk.faces += int(chunk.m[i][j][k].CountExposedFaces(chunk, i, j, k))
+				b := chunk.m[i][j][k]
+				faces[j/sectionHeight][blocks[b.t].pass] += int(b.CountExposedFaces(chunk, i, j, k))
 			}
 		}
 	}
-	if chunk.faces == 0 {
+	for s := range chunk.sections {
+		for pass := renderPass(0); pass < passCount; pass++ {
+			chunk.genSection(s, pass, faces[s][pass])
+		}
+		chunk.visibility[s] = chunk.computeVisibility(s)
+	}
+}
+
+//genSection builds the mesh of the render pass of the section with the counted exposed faces
+func (chunk *Chunk) genSection(s int, pass renderPass, faces int) {
+	mesh := chunk.sections[s][pass]
+	if faces == 0 {
+		if mesh != nil {
+			mesh.Free()
+			chunk.sections[s][pass] = nil
+		}
 		return
 	}
-	vertexBuffer := make([]float32, chunk.faces*6*3)
-	uvBuffer := make([]float32, chunk.faces*6*2)
-	var vbOffset, uvOffset int
+	vertices := make([]float32, faces*6*chunkVertexSize)
+	var offset int
 	for x := 0; x < chunkSize; x++ {
-		for y := 0; y < 256; y++ {
+		for y := s * sectionHeight; y < (s+1)*sectionHeight; y++ {
 			for z := 0; z < chunkSize; z++ {
 				b := chunk.m[x][y][z]
-				if b.faces == 0 {
+				if b.faces == 0 || blocks[b.t].pass != pass {
 					continue
 				}
-				b.MakeCube(chunk, x, y, z, vertexBuffer[vbOffset:], uvBuffer[uvOffset:])
-				vbOffset += int(b.faces) * 6 * 3
-				uvOffset += int(b.faces) * 6 * 2
+				b.MakeCube(chunk, x, y, z, vertices[offset:])
+				offset += int(b.faces) * 6 * chunkVertexSize
 			}
 		}
 	}
-	chunk.vertexBuffer = glw.NewBuffer(vertexBuffer)
-	chunk.uvBuffer = glw.NewBuffer(uvBuffer)
-	return
+	var err error
+	if mesh != nil {
+		err = mesh.Update(vertices)
+	} else {
+		chunk.sections[s][pass], err = chunk.w.arena.Alloc(vertices)
+	}
+	if err != nil {
+		log.Println(err)
+	}
 }
 
-func (chunk *Chunk) Draw(vert, uv glw.VertexAttrib) {
-	chunk.vertexBuffer.BindBuffer()
-	vert.EnableVertexAttribArray()
-	vert.VertexAttribPointer(3, gl.FLOAT, false, 0, nil)
-
-	chunk.uvBuffer.BindBuffer()
-	uv.EnableVertexAttribArray()
-	uv.VertexAttribPointer(2, gl.FLOAT, false, 0, nil)
+//hasMesh reports whether the section has the faces of any render pass
+func (chunk *Chunk) hasMesh(s int) bool {
+	for _, mesh := range chunk.sections[s] {
+		if mesh != nil {
+			return true
+		}
+	}
+	return false
+}
 
-	gl.DrawArrays(gl.TRIANGLES, 0, int32(6*2*3*chunk.faces))
+//sectionBounds returns the corners of the box of the section in world coordinates
+func (chunk *Chunk) sectionBounds(s int) (min, max mgl32.Vec3) {
+	min = mgl32.Vec3{float32(chunk.P * chunkSize), float32(s * sectionHeight), float32(chunk.Q * chunkSize)}
+	return min, min.Add(mgl32.Vec3{chunkSize, sectionHeight, chunkSize})
 }
 
-//Delete deletes the buffers of the chunk
+//Delete frees the meshes of the chunk
 func (chunk *Chunk) Delete() {
-	chunk.vertexBuffer.Delete()
-	chunk.uvBuffer.Delete()
+	for s := range chunk.sections {
+		for pass, mesh := range chunk.sections[s] {
+			if mesh != nil {
+				mesh.Free()
+				chunk.sections[s][pass] = nil
+			}
+		}
+	}
+}
+
+//blockType returns the type of the block at chunk position,
+//positions outside of the chunk are looked up in the neighbouring chunks
+func (chunk *Chunk) blockType(x, y, z int) itemType {
+	if y < 0 || y > 255 {
+		return EmptyItem
+	}
+	if x < 0 || x >= chunkSize || z < 0 || z >= chunkSize {
+		return chunk.w.GetBlock(chunk.P*chunkSize+x, y, chunk.Q*chunkSize+z)
+	}
+	return chunk.m[x][y][z].t
 }
 
 type Block struct {
@@ -124,49 +203,63 @@ type Block struct {
 	f     [6]bool
 }
 
+//CountExposedFaces finds the faces which aren't hidden by the neighbours, the cross shaped blocks
+//have their 2 quads always visible
 func (b *Block) CountExposedFaces(chunk *Chunk, x, y, z int) (faces int16) {
 	if b.t == EmptyItem {
 		b.faces = 0
 		return
 	}
-	if b.f[0] = y == 0 || chunk.m[x][y-1][z].t == EmptyItem; b.f[0] { //Bottom
+	if blocks[b.t].shape == crossShape {
+		b.faces = 2
+		return b.faces
+	}
+	if b.f[0] = y == 0 || faceVisible(b.t, chunk.blockType(x, y-1, z)); b.f[0] { //Bottom
 		faces++
 	}
-	if b.f[1] = y == 255 || chunk.m[x][y+1][z].t == EmptyItem; b.f[1] { //Top
+	if b.f[1] = y == 255 || faceVisible(b.t, chunk.blockType(x, y+1, z)); b.f[1] { //Top
 		faces++
 	}
-	if b.f[2] = z == chunkSize-1 || chunk.m[x][y][z+1].t == EmptyItem; b.f[2] { //Front
+	if b.f[2] = faceVisible(b.t, chunk.blockType(x, y, z+1)); b.f[2] { //Front
 		faces++
 	}
-	if b.f[3] = z == 0 || chunk.m[x][y][z-1].t == EmptyItem; b.f[3] { //Back
+	if b.f[3] = faceVisible(b.t, chunk.blockType(x, y, z-1)); b.f[3] { //Back
 		faces++
 	}
-	if b.f[4] = x == 0 || chunk.m[x-1][y][z].t == EmptyItem; b.f[4] { //Left
+	if b.f[4] = faceVisible(b.t, chunk.blockType(x-1, y, z)); b.f[4] { //Left
 		faces++
 	}
-	if b.f[5] = x == chunkSize-1 || chunk.m[x+1][y][z].t == EmptyItem; b.f[5] { //Right
+	if b.f[5] = faceVisible(b.t, chunk.blockType(x+1, y, z)); b.f[5] { //Right
 		faces++
 	}
 	b.faces = faces
 	return
 }
 
-func (b *Block) MakeCube(chunk *Chunk, x, y, z int, vertexBuffer, uvBuffer []float32) {
-	var vbOffset, uvOffset int
+//MakeCube writes the interleaved vertices of the exposed faces
+func (b *Block) MakeCube(chunk *Chunk, x, y, z int, vertices []float32) {
+	positions, coords := cubeVertices, uvs
+	faces := [6]bool{}
+	if blocks[b.t].shape == crossShape {
+		positions, coords = crossVertices, crossUVs
+		faces[0], faces[1] = true, true
+	} else {
+		faces = b.f
+	}
+	var offset int
 	for f := 0; f < 6; f++ {
-		if !b.f[f] {
+		if !faces[f] {
 			continue
 		}
 		for i := 0; i < 6; i++ {
-			vertexBuffer[vbOffset+i*3+0] = cubeVertices[f*6*3+i*3+0] + float32(x) + (float32(chunk.P) * chunkSize)
-			vertexBuffer[vbOffset+i*3+1] = cubeVertices[f*6*3+i*3+1] + float32(y)
-			vertexBuffer[vbOffset+i*3+2] = cubeVertices[f*6*3+i*3+2] + float32(z) + (float32(chunk.Q) * chunkSize)
-
-			uvBuffer[uvOffset+i*2+0] = uvs[f*6*2+i*2+0] + (texWidth * float32(b.t-1))
-			uvBuffer[uvOffset+i*2+1] = uvs[f*6*2+i*2+1]
+			v := vertices[offset+i*chunkVertexSize:]
+			v[0] = positions[f*6*3+i*3+0] + float32(x) + (float32(chunk.P) * chunkSize)
+			v[1] = positions[f*6*3+i*3+1] + float32(y)
+			v[2] = positions[f*6*3+i*3+2] + float32(z) + (float32(chunk.Q) * chunkSize)
+			v[3] = coords[f*6*2+i*2+0] + (texWidth * float32(blocks[b.t].texture))
+			v[4] = coords[f*6*2+i*2+1]
 		}
-		vbOffset += 6 * 3
-		uvOffset += 6 * 2
+		offset += 6 * chunkVertexSize
 	}
 }
 
@@ -178,10 +271,16 @@ const (
 	SandItem
 	StoneItem
 	BrickItem
+	LeavesItem
+	GlassItem
+	WaterItem
+	StainedGlassItem
+	FlowerItem
 )
 
 const (
-	itemsCount = 8
+	//itemsCount is the number of the columns of the texture atlas
+	itemsCount = 16
 	texWidth   = 1 / float32(itemsCount)
 	texHeight  = 1 / float32(3)
 )
@@ -275,3 +374,37 @@ var cubeVertices = []float32{
 	1.0, 1.0, 0.0,
 	1.0, 1.0, 1.0,
 }
+
+//crossVertices are the two diagonal quads of the plants, the cutout pass draws both of their sides
+var crossVertices = []float32{
+	0.0, 0.0, 0.0,
+	1.0, 0.0, 1.0,
+	0.0, 1.0, 0.0,
+	1.0, 0.0, 1.0,
+	1.0, 1.0, 1.0,
+	0.0, 1.0, 0.0,
+
+	1.0, 0.0, 0.0,
+	0.0, 0.0, 1.0,
+	1.0, 1.0, 0.0,
+	0.0, 0.0, 1.0,
+	0.0, 1.0, 1.0,
+	1.0, 1.0, 0.0,
+}
+
+//crossUVs map the side texture of the block on the quads
+var crossUVs = []float32{
+	0.0, 2 * texHeight,
+	texWidth, 2 * texHeight,
+	0.0, texHeight,
+	texWidth, 2 * texHeight,
+	texWidth, texHeight,
+	0.0, texHeight,
+
+	0.0, 2 * texHeight,
+	texWidth, 2 * texHeight,
+	0.0, texHeight,
+	texWidth, 2 * texHeight,
+	texWidth, texHeight,
+	0.0, texHeight,
+}
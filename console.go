@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"sort"
+	"strings"
+)
+
+//command is the console command handler, args are the words after the command name
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+//console reads the commands from the reader, e.g. the stdin, and runs them in the update step
+//so they can change the state of the renderers
+type console struct {
+	lines    chan string
+	commands map[string]command
+}
+
+func newConsole(r io.Reader) *console {
+	c := &console{
+		lines:    make(chan string, 16),
+		commands: make(map[string]command),
+	}
+	c.Register("help", "help - lists the commands", func(args []string) error {
+		names := make([]string, 0, len(c.commands))
+		for name := range c.commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			log.Println(c.commands[name].usage)
+		}
+		return nil
+	})
+	go c.read(r)
+	return c
+}
+
+func (c *console) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		c.lines <- scanner.Text()
+	}
+}
+
+//Register adds the command, usage is shown by the help command
+func (c *console) Register(name, usage string, run func(args []string) error) {
+	c.commands[name] = command{usage: usage, run: run}
+}
+
+//Update runs the commands read since the last update
+func (c *console) Update(dt float64) {
+	for {
+		select {
+		case line := <-c.lines:
+			c.run(line)
+		default:
+			return
+		}
+	}
+}
+
+func (c *console) run(line string) {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return
+	}
+	cmd, ok := c.commands[words[0]]
+	if !ok {
+		log.Printf("unknown command %s, type help to list the commands", words[0])
+		return
+	}
+	if err := cmd.run(words[1:]); err != nil {
+		log.Printf("%s: %s\nusage: %s", words[0], err, cmd.usage)
+	}
+}
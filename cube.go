@@ -1,13 +1,18 @@
 package main
 
 import (
-	"bytes"
+	"flag"
+	"fmt"
+	"image"
 	"log"
+	"math"
+	"os"
 	"runtime"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/microo8/craft/glw"
+	"github.com/microo8/craft/physics"
 )
 
 func init() {
@@ -15,30 +20,87 @@ func init() {
 	runtime.LockOSThread()
 }
 
-const (
-	windowWidth  = 1920
-	windowHeight = 1024
-)
+var snapshot = flag.String("snapshot", "", "render one frame offscreen into the png `file` and exit")
+var shadows = flag.String("shadows", "", "`quality` of the sun shadows: off, low or high, overrides the settings")
 
 func main() {
+	flag.Parse()
+	s, err := loadSettings()
+	if err != nil && !os.IsNotExist(err) {
+		log.Println(err)
+	}
+	if *shadows != "" {
+		s.Shadows = *shadows
+	}
+	quality, err := parseShadowQuality(s.Shadows)
+	if err != nil {
+		panic(err)
+	}
 	cam := &glw.Camera{
-		Pos:         mgl32.Vec3{100, 50, 100},
-		Rotation:    mgl32.Vec3{0, 0, 0},
-		Up:          mgl32.Vec3{0, 1, 0},
-		Model:       mgl32.Ident4(),
-		Perspective: mgl32.Perspective(mgl32.DegToRad(45.0), float32(windowWidth)/windowHeight, 0.1, 3000.0),
-	}
-	cam.Update()
-	app, err := glw.NewApp("Cube", windowWidth, windowHeight, cam)
+		Pos:      mgl32.Vec3{100, 50, 100},
+		Rotation: mgl32.Vec3{0, 0, 0},
+		Up:       mgl32.Vec3{0, 1, 0},
+		Model:    mgl32.Ident4(),
+	}
+	//the aspect ratio is set by the app from the framebuffer size
+	cam.SetPerspective(mgl32.DegToRad(45.0), 16.0/9, 0.1, 3000.0)
+	config := glw.DefaultWindowConfig()
+	config.Title = "craft"
+	config.Width, config.Height = 1920, 1024
+	config.Samples = 4
+	config.Headless = *snapshot != ""
+	app, err := glw.NewApp(config, cam)
 	if err != nil {
 		panic(err)
 	}
 	defer app.Terminate()
 
-	rr := newWorld()
-	app.Renderers = append(app.Renderers, rr)
+	for action, bindings := range defaultBindings {
+		app.Input.Bind(action, bindings...)
+	}
+	if err := app.Input.LoadBindings(bindingsPath); err != nil && !os.IsNotExist(err) {
+		log.Println(err)
+	}
+
+	post, err := newPostChain(app, s.Post)
+	if err != nil {
+		panic(err)
+	}
+
+	rr := newWorld(cam, app.Input, quality, !app.Headless())
+	if err := app.AddRenderer(glw.WorldLayer, rr); err != nil {
+		panic(err)
+	}
+	if err := app.AddRenderer(glw.WorldLayer, newLODTerrain(rr)); err != nil {
+		panic(err)
+	}
+	//the sky is drawn behind the terrain so it's rendered after it
+	if err := app.AddRenderer(glw.WorldLayer, newSky(rr)); err != nil {
+		panic(err)
+	}
+	if err := app.AddRenderer(glw.WorldLayer, newTranslucentBlocks(rr)); err != nil {
+		panic(err)
+	}
+	if err := app.AddRenderer(glw.OverlayLayer, newHighlight(rr)); err != nil {
+		panic(err)
+	}
+
+	if *snapshot != "" {
+		app.RenderFrame(1)
+		if err := glw.SavePNG(*snapshot, app.ReadPixels()); err != nil {
+			panic(err)
+		}
+		return
+	}
 
-	app.SetCameraCursor(0.001, 5)
+	c := newConsole(os.Stdin)
+	registerTimeCommands(c, rr)
+	registerShadowCommands(c, rr)
+	registerPostCommands(c, post)
+	registerSettingsCommands(c, rr, post)
+	app.Updaters = append(app.Updaters, appActions(app), c)
+	app.Timestep = physics.Timestep
+	app.SetCameraCursor(0.001)
 
 	if err = app.Run(); err != nil {
 		panic(err)
@@ -46,116 +108,399 @@ func main() {
 }
 
 type world struct {
+	shader  *glw.ShaderProgram
+	pp      *glw.Preprocessor //defines of the world program
 	p       glw.Program
 	texture glw.Texture
+	//chunkFade are the dither levels of the chunks fading between the terrain tiles
+	chunkFade  glw.Texture
+	fadeLevels *image.RGBA
+	frame      *glw.UniformBuffer
+	arena      *glw.MeshArena
+	visible    [passCount][]*glw.ArenaMesh
+	//translucent are the visible translucent meshes drawn by the translucentBlocks renderer
+	translucent []translucentSection
+	reached     []bool //sections reached by the visibility search
+	queue       []sectionNode
+	lod         *lodTerrain
+	shadows     *shadowMap
+	time        float64
+	dayTime     float64 //hours of the day, saved in worldStatePath
+	persistent  bool    //the state of the world is loaded and saved, headless snapshots start at startTime
 
-	mvp  glw.UniformLocation
-	vert glw.VertexAttrib
-	uv   glw.VertexAttrib
+	app   *glw.App
+	title string
+	//drawn, culled and occluded are the numbers of the chunk sections in the last frame
+	drawn     int
+	culled    int
+	occluded  int
+	statsTime float64
 
-	chunks []*Chunk
+	chunks map[[2]int]*Chunk
 	player *Player
+	cam    *glw.Camera
+	input  *glw.Input
+
+	target    RaycastHit
+	hasTarget bool
+	selected  itemType
 }
 
-func newWorld() *world {
-	w := new(world)
-	w.player = &Player{}
-	vs := glw.ShaderSource{Type: gl.VERTEX_SHADER, Source: bytes.NewBufferString(vertexShaderSrc)}
-	fs := glw.ShaderSource{Type: gl.FRAGMENT_SHADER, Source: bytes.NewBufferString(fragmentShaderSrc)}
-	p, err := glw.NewProgram(vs, fs)
+func newWorld(cam *glw.Camera, input *glw.Input, shadows shadowQuality, persistent bool) *world {
+	w := &world{cam: cam, input: input, chunks: make(map[[2]int]*Chunk), selected: DirtItem, persistent: persistent}
+	w.player = newPlayer(cam, input)
+	w.shadows = newShadowMap(w, shadows)
+	w.setTime(startTime)
+	if persistent {
+		state, err := loadWorldState(worldStatePath)
+		if err != nil && !os.IsNotExist(err) {
+			log.Println(err)
+		}
+		w.setTime(state.Time)
+	}
+	return w
+}
+
+//Init creates the program, texture and vertex array of the world
+func (w *world) Init(app *glw.App) error {
+	w.app, w.title = app, app.Title()
+	w.pp = glw.NewPreprocessor(glw.DefaultShaderRoot)
+	w.shadows.setDefines(w.pp)
+	w.chunkFade, w.fadeLevels = newChunkFade(w.pp)
+	shader, err := glw.NewShaderProgram(w.pp,
+		glw.ShaderFile{Type: gl.VERTEX_SHADER, Path: "vertex_shader.glsl"},
+		glw.ShaderFile{Type: gl.FRAGMENT_SHADER, Path: "fragment_shader.glsl"},
+	)
 	if err != nil {
-		panic(err)
+		w.chunkFade.Delete()
+		return err
 	}
-	w.p = p
-	w.p.UseProgram()
+	w.shader = shader
 
-	texture, err := glw.NewTextureFromFile("assets/textures/texture.png")
+	texture, err := glw.NewTextureFromFile("assets/textures/texture.png", glw.TextureOptions{
+		MinFilter:  gl.NEAREST_MIPMAP_LINEAR,
+		MagFilter:  gl.NEAREST,
+		WrapS:      gl.CLAMP_TO_EDGE,
+		WrapT:      gl.CLAMP_TO_EDGE,
+		Mipmaps:    true,
+		Anisotropy: 4,
+	})
 	if err != nil {
-		log.Fatalln(err)
+		w.shader.Delete()
+		w.chunkFade.Delete()
+		return err
 	}
 	w.texture = texture
 
-	// Configure the vertex data
-	glw.NewVertexArray().BindVertexArray()
+	w.frame, err = glw.NewUniformBuffer(perFrameBinding, perFrame{})
+	if err != nil {
+		w.shader.Delete()
+		w.texture.Delete()
+		w.chunkFade.Delete()
+		return err
+	}
 
-	w.mvp = w.p.GetUniformLocation("mvp")
-	w.vert = w.p.GetAttribLocation("vert")
-	w.uv = w.p.GetAttribLocation("uv")
+	if err := w.shadows.init(); err != nil {
+		w.shader.Delete()
+		w.texture.Delete()
+		w.chunkFade.Delete()
+		w.frame.Delete()
+		return err
+	}
 
-	return w
+	w.arena = glw.NewMeshArena(w.shader.Program(), chunkLayout, arenaPageVertices)
+	if err := w.shader.SetOnReload(w.setProgram); err != nil {
+		w.shader.Delete()
+		w.texture.Delete()
+		w.chunkFade.Delete()
+		w.frame.Delete()
+		w.shadows.delete()
+		w.arena.Delete()
+		return err
+	}
+	return nil
+}
+
+//setProgram sets the program and the vertex arrays of the mesh arena to its attribute locations,
+//it's called again when the shaders are reloaded
+func (w *world) setProgram(p glw.Program) error {
+	w.p = p
+	if err := w.arena.SetProgram(p); err != nil {
+		return err
+	}
+	if err := p.BindUniformBlock("PerFrame", w.frame); err != nil {
+		return err
+	}
+	if err := w.shadows.setProgram(p); err != nil {
+		return err
+	}
+	if err := p.SetSampler("chunkFade", chunkFadeUnit); err != nil {
+		return err
+	}
+	return p.SetSampler("tex", 0)
+}
+
+//Destroy saves the state of the world if it's persistent and deletes the chunks and the gl resources of the world
+func (w *world) Destroy() {
+	if w.persistent {
+		if err := saveWorldState(worldStatePath, worldState{Time: w.dayTime}); err != nil {
+			log.Println(err)
+		}
+	}
+	for key, chunk := range w.chunks {
+		chunk.Delete()
+		delete(w.chunks, key)
+	}
+	w.arena.Delete()
+	w.shadows.delete()
+	w.frame.Delete()
+	w.texture.Delete()
+	w.chunkFade.Delete()
+	w.shader.Delete()
 }
 
-//Render rotates the camera around the cube
-func (w *world) Render(projView mgl32.Mat4, elapsed float64) {
-	p := round(w.player.X / chunkSize)
-	q := round(w.player.Y / chunkSize)
+//Update moves the player, streams the chunks around it and edits the targeted block
+func (w *world) Update(dt float64) {
+	w.shader.Poll()
+	w.shadows.update()
+	w.time += dt
+	w.advanceTime(dt)
+	w.player.Update(w, dt)
+
+	p, q := w.player.Chunk()
 	//delete far chunks
-	var deleteIndices []int
-	for i, chunk := range w.chunks {
+	for key, chunk := range w.chunks {
 		dp := chunk.P - p
 		dq := chunk.Q - q
-		if abs(dp) >= chunkDeleteRadius && abs(dq) >= chunkDeleteRadius {
-			deleteIndices = append(deleteIndices, i)
+		if abs(dp) >= chunkDeleteRadius || abs(dq) >= chunkDeleteRadius {
+			chunk.Delete()
+			delete(w.chunks, key)
 		}
 	}
-	for _, i := range deleteIndices {
-		w.chunks[i].Delete()
-		w.chunks = append(w.chunks[:i], w.chunks[i+1:]...)
-	}
-	//create new chunks in render radius
-	for i := -chunkRenderRadius; i <= chunkRenderRadius; i++ {
-		for j := -chunkRenderRadius; j <= chunkRenderRadius; j++ {
+	//create new chunks in render radius, they are meshed after all of them are created
+	//together with the loaded neighbours, whose border faces may be hidden by the new blocks
+	var remesh []*Chunk
+	queued := make(map[*Chunk]bool)
+	for i := p - chunkRenderRadius; i <= p+chunkRenderRadius; i++ {
+		for j := q - chunkRenderRadius; j <= q+chunkRenderRadius; j++ {
 			if w.getChunk(i, j) != nil {
 				continue
 			}
-			w.chunks = append(w.chunks, NewChunk(i, j))
+			chunk := NewChunk(w, i, j)
+			w.chunks[[2]int{i, j}] = chunk
+			for _, c := range append(w.neighbourChunks(i, j), chunk) {
+				if !queued[c] {
+					queued[c] = true
+					remesh = append(remesh, c)
+				}
+			}
+		}
+	}
+	for _, chunk := range remesh {
+		chunk.genBuffers()
+	}
+	if w.arena.Fragmentation() > arenaMaxFragments {
+		if err := w.arena.Defragment(); err != nil {
+			log.Println(err)
 		}
 	}
-	//Render
-	gl.ClearColor(0.53, 0.81, 0.92, 1.00)
+	w.updateTarget()
+	w.handleInput()
+	w.updateUnderwater()
+
+	w.statsTime += dt
+	if w.statsTime >= 1 {
+		w.statsTime = 0
+		w.app.SetTitle(fmt.Sprintf("%s - sections drawn %d, culled %d, occluded %d", w.title, w.drawn, w.culled, w.occluded))
+	}
+}
+
+//Render uploads the per frame data shared by the shaders and draws the chunk sections around the player
+//which are in the view frustum and aren't hidden behind the opaque blocks
+func (w *world) Render(projView mgl32.Mat4, alpha float64) {
+	//the player moves the camera so projView passed by the app is one frame late
+	w.player.Interpolate(alpha)
+	sunDir := sunDirection(w.dayTime)
+	zenith, horizon, ambient := skyColors(sunDir[1])
+	fogEnd := float32(lodRadius * chunkSize)
+	err := w.frame.Set(perFrame{
+		ProjView:     w.cam.ProjView,
+		CameraPos:    w.cam.Pos,
+		Time:         float32(w.time + alpha*physics.Timestep),
+		SunDir:       sunDir,
+		Ambient:      ambient,
+		HorizonColor: horizon,
+		FogStart:     0.6 * fogEnd,
+		ZenithColor:  zenith,
+		FogEnd:       fogEnd,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	w.shadows.render()
+
+	p, q := w.player.Chunk()
+	//the sky covers the background, the clear colour shows only where the sky isn't drawn
+	gl.ClearColor(horizon[0], horizon[1], horizon[2], 1.00)
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
-	w.mvp.UniformMatrix4fv(1, false, projView)
+	w.p.UseProgram()
+	w.updateChunkFade(p, q)
+	w.texture.BindToUnit(0)
+	w.shadows.bindTexture()
 
+	for pass := range w.visible {
+		w.visible[pass] = w.visible[pass][:0]
+	}
+	w.translucent = w.translucent[:0]
+	w.drawn, w.culled = 0, 0
+	w.visibleSections(w.cam.Frustum())
+	//the sections in the radius which weren't reached are hidden behind the others
+	sections := 0
 	for _, chunk := range w.chunks {
-		dp := chunk.P - p
-		dq := chunk.Q - q
-		if abs(dp) <= chunkRenderRadius && abs(dq) <= chunkRenderRadius {
-			chunk.Draw(w.vert, w.uv)
+		if abs(chunk.P-p) > chunkRenderRadius || abs(chunk.Q-q) > chunkRenderRadius || !w.fullDetail(chunk.P, chunk.Q) {
+			continue
+		}
+		for s := range chunk.sections {
+			if chunk.hasMesh(s) {
+				sections++
+			}
 		}
 	}
+	w.occluded = sections - w.drawn - w.culled
+	if err := w.p.SetFloat("alphaTest", 0); err != nil {
+		log.Println(err)
+	}
+	w.arena.Draw(gl.TRIANGLES, w.visible[opaquePass])
+	//both sides of the plants and the leaves are visible
+	gl.Disable(gl.CULL_FACE)
+	if err := w.p.SetFloat("alphaTest", cutoutAlphaTest); err != nil {
+		log.Println(err)
+	}
+	w.arena.Draw(gl.TRIANGLES, w.visible[cutoutPass])
+	gl.Enable(gl.CULL_FACE)
+}
+
+//updateUnderwater tints the post-processed view when the eyes of the player are in the water,
+//it's updated before the app decides whether the post-processing chain is active in the frame
+func (w *world) updateUnderwater() {
+	post := w.app.PostChain()
+	if post == nil {
+		return
+	}
+	pass := post.Pass("underwater")
+	if pass == nil {
+		return
+	}
+	pos := w.player.Eye()
+	pass.Skip = w.GetBlock(int(math.Floor(float64(pos[0]))), int(math.Floor(float64(pos[1]))), int(math.Floor(float64(pos[2])))) != WaterItem
 }
 
 func (w *world) getChunk(p, q int) *Chunk {
-	for _, chunk := range w.chunks {
-		if chunk.P == p && chunk.Q == q {
-			return chunk
+	return w.chunks[[2]int{p, q}]
+}
+
+//neighbourChunks returns the loaded chunks sharing the side faces with the chunk
+func (w *world) neighbourChunks(p, q int) []*Chunk {
+	var chunks []*Chunk
+	for _, n := range chunkNeighbours {
+		if chunk := w.getChunk(p+n[0], q+n[1]); chunk != nil {
+			chunks = append(chunks, chunk)
 		}
 	}
-	return nil
+	return chunks
 }
 
-type Player struct {
-	X, Y, Z float32
+//updateTarget finds the block targeted by the player
+func (w *world) updateTarget() {
+	w.target, w.hasTarget = w.Raycast(w.player.Eye(), w.cam.Rotation.Sub(w.cam.Pos), blockReach)
+}
+
+//GetBlock returns the type of the block at world position, EmptyItem if the chunk isn't loaded
+func (w *world) GetBlock(x, y, z int) itemType {
+	if y < 0 || y > 255 {
+		return EmptyItem
+	}
+	chunk := w.getChunk(floorDiv(x, chunkSize), floorDiv(z, chunkSize))
+	if chunk == nil {
+		return EmptyItem
+	}
+	return chunk.m[x-chunk.P*chunkSize][y][z-chunk.Q*chunkSize].t
+}
+
+//SetBlock sets the type of the block at world position and re-meshes the affected chunks
+func (w *world) SetBlock(x, y, z int, t itemType) {
+	if y < 0 || y > 255 {
+		return
+	}
+	p, q := floorDiv(x, chunkSize), floorDiv(z, chunkSize)
+	chunk := w.getChunk(p, q)
+	if chunk == nil {
+		return
+	}
+	dx, dz := x-p*chunkSize, z-q*chunkSize
+	if chunk.m[dx][y][dz].t == t {
+		return
+	}
+	chunk.m[dx][y][dz].t = t
+	chunk.genBuffers()
+	//the faces of the neighbours touching the border block may have changed
+	neighbours := [][2]int{}
+	if dx == 0 {
+		neighbours = append(neighbours, [2]int{p - 1, q})
+	}
+	if dx == chunkSize-1 {
+		neighbours = append(neighbours, [2]int{p + 1, q})
+	}
+	if dz == 0 {
+		neighbours = append(neighbours, [2]int{p, q - 1})
+	}
+	if dz == chunkSize-1 {
+		neighbours = append(neighbours, [2]int{p, q + 1})
+	}
+	for _, n := range neighbours {
+		if neighbour := w.getChunk(n[0], n[1]); neighbour != nil {
+			neighbour.genBuffers()
+		}
+	}
 }
 
-var vertexShaderSrc = `
-#version 330
-uniform mat4 mvp;
-in vec3 vert;
-in vec2 uv;
-out vec2 fragUV;
-void main() {
-    fragUV = uv;
-    gl_Position = mvp * vec4(vert, 1);
+//Solid reports whether the player collides with the block
+func (w *world) Solid(x, y, z int) bool {
+	return blocks[w.GetBlock(x, y, z)].solid
 }
-`
 
-var fragmentShaderSrc = `
-#version 330
-uniform sampler2D tex;
-in vec2 fragUV;
-out vec4 color;
-void main() {
-    color = texture(tex, fragUV);
+//handleInput breaks the targeted block, places the selected one on the targeted face
+//and selects the block to place
+func (w *world) handleInput() {
+	for i, t := range []itemType{DirtItem, SandItem, StoneItem, BrickItem, LeavesItem, GlassItem, WaterItem, StainedGlassItem, FlowerItem} {
+		if w.input.JustPressed(fmt.Sprintf("slot%d", i+1)) {
+			w.selected = t
+		}
+	}
+	if !w.hasTarget {
+		return
+	}
+	b := w.target.Block
+	switch {
+	case w.input.JustPressed("break"):
+		w.SetBlock(b[0], b[1], b[2], EmptyItem)
+	case w.input.JustPressed("place"):
+		n := w.target.Normal
+		if n == [3]int{} {
+			return
+		}
+		x, y, z := b[0]+n[0], b[1]+n[1], b[2]+n[2]
+		if !replaceable(w.GetBlock(x, y, z)) {
+			return
+		}
+		//the collisions don't push the player out of the solid block placed into it
+		if blocks[w.selected].solid && physics.BlockBox(x, y, z).Intersects(w.player.body.Box()) {
+			return
+		}
+		w.SetBlock(x, y, z, w.selected)
+	default:
+		return
+	}
+	w.updateTarget()
 }
-`
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	//dayLength is the duration of the whole day in seconds
+	dayLength = 20 * 60
+	//worldStatePath is the file the state of the world is saved to
+	worldStatePath = "save/world.json"
+	startTime      = 8
+)
+
+//worldState is the saved state of the world
+type worldState struct {
+	Time float64 `json:"time"` //hours of the day
+}
+
+func loadWorldState(path string) (worldState, error) {
+	state := worldState{Time: startTime}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("%s: %s", path, err)
+	}
+	return state, nil
+}
+
+func saveWorldState(path string, state worldState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+//advanceTime moves the time of the day by the seconds
+func (w *world) advanceTime(seconds float64) {
+	w.setTime(w.dayTime + seconds*24/dayLength)
+}
+
+//setTime sets the time of the day in hours, it wraps around midnight
+func (w *world) setTime(hours float64) {
+	w.dayTime = math.Mod(hours, 24)
+	if w.dayTime < 0 {
+		w.dayTime += 24
+	}
+}
+
+//sunDirection returns the direction to the sun at the time of the day,
+//it rises in the east at 6 and sets in the west at 18, the moon is on the opposite side
+func sunDirection(hours float64) mgl32.Vec3 {
+	a := (hours - 6) / 24 * 2 * math.Pi
+	return mgl32.Vec3{float32(math.Cos(a)), float32(math.Sin(a)), 0.3}.Normalize()
+}
+
+//skyColors returns the colour of the sky in the zenith and at the horizon and the ambient light
+//for the height of the sun
+func skyColors(sunHeight float32) (zenith, horizon mgl32.Vec3, ambient float32) {
+	var (
+		dayZenith     = mgl32.Vec3{0.25, 0.5, 0.9}
+		dayHorizon    = mgl32.Vec3{0.53, 0.81, 0.92}
+		sunsetZenith  = mgl32.Vec3{0.3, 0.3, 0.6}
+		sunsetHorizon = mgl32.Vec3{0.9, 0.5, 0.3}
+		nightZenith   = mgl32.Vec3{0.01, 0.01, 0.04}
+		nightHorizon  = mgl32.Vec3{0.04, 0.05, 0.1}
+	)
+	mix := func(a, b mgl32.Vec3, t float32) mgl32.Vec3 {
+		return a.Mul(1 - t).Add(b.Mul(t))
+	}
+	switch {
+	case sunHeight >= 0.3:
+		zenith, horizon = dayZenith, dayHorizon
+	case sunHeight >= 0:
+		t := sunHeight / 0.3
+		zenith, horizon = mix(sunsetZenith, dayZenith, t), mix(sunsetHorizon, dayHorizon, t)
+	case sunHeight >= -0.2:
+		t := -sunHeight / 0.2
+		zenith, horizon = mix(sunsetZenith, nightZenith, t), mix(sunsetHorizon, nightHorizon, t)
+	default:
+		zenith, horizon = nightZenith, nightHorizon
+	}
+	ambient = 0.15 + 0.85*float32(smoothstep(-0.2, 0.3, float64(sunHeight)))
+	return zenith, horizon, ambient
+}
+
+func smoothstep(edge0, edge1, x float64) float64 {
+	t := math.Max(0, math.Min(1, (x-edge0)/(edge1-edge0)))
+	return t * t * (3 - 2*t)
+}
+
+//namedTimes are the times accepted by the time set command
+var namedTimes = map[string]float64{
+	"sunrise":  6,
+	"day":      8,
+	"noon":     12,
+	"sunset":   18,
+	"night":    21,
+	"midnight": 0,
+}
+
+//registerTimeCommands adds the time command controlling the time of the day
+func registerTimeCommands(c *console, w *world) {
+	c.Register("time", "time [set <hours>|set day|noon|night|...|add <hours>] - shows or changes the time of the day", func(args []string) error {
+		if len(args) == 0 {
+			log.Printf("time is %02d:%02d", int(w.dayTime), int(w.dayTime*60)%60)
+			return nil
+		}
+		if len(args) != 2 {
+			return fmt.Errorf("wrong number of arguments")
+		}
+		hours, ok := namedTimes[args[1]]
+		if !ok {
+			var err error
+			if hours, err = strconv.ParseFloat(args[1], 64); err != nil {
+				return fmt.Errorf("invalid hours %s", args[1])
+			}
+		}
+		switch args[0] {
+		case "set":
+			w.setTime(hours)
+		case "add":
+			w.setTime(w.dayTime + hours)
+		default:
+			return fmt.Errorf("unknown subcommand %s", args[0])
+		}
+		return nil
+	})
+}
@@ -0,0 +1,20 @@
+package main
+
+import "github.com/go-gl/mathgl/mgl32"
+
+//perFrameBinding is the binding point of the per frame uniform buffer
+const perFrameBinding = 0
+
+//perFrame is the data shared by all shaders which is uploaded once per frame,
+//it's the PerFrame uniform block in assets/shaders/per_frame.glsl
+type perFrame struct {
+	ProjView     mgl32.Mat4
+	CameraPos    mgl32.Vec3
+	Time         float32 //seconds since the start
+	SunDir       mgl32.Vec3
+	Ambient      float32
+	HorizonColor mgl32.Vec3
+	FogStart     float32
+	ZenithColor  mgl32.Vec3
+	FogEnd       float32
+}
@@ -2,141 +2,371 @@ package glw
 
 import (
 	"fmt"
+	"log"
 	"math"
+	"reflect"
+	"time"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
 	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 )
 
-//Camera the world camera
-type Camera struct {
-	Pos         mgl32.Vec3
-	Rotation    mgl32.Vec3
-	Up          mgl32.Vec3
-	Perspective mgl32.Mat4
-	Model       mgl32.Mat4
-	ProjView    mgl32.Mat4
-}
-
-//Update recalculates MVP
-func (cam *Camera) Update() {
-	view := mgl32.LookAtV(cam.Pos, cam.Rotation, cam.Up)
-	cam.ProjView = cam.Perspective.Mul4(view).Mul4(cam.Model)
-}
-
 //App wraps the window creation and gl initialization and the main loop
 type App struct {
-	window    *glfw.Window
-	Renderers []Renderer
-	Camera    *Camera
+	window   *glfw.Window
+	config   WindowConfig
+	windowed [4]int //position and size of the window before switching to fullscreen
+	layers   [layerCount][]Renderer
+	removed  []Renderer //removed at the end of the frame
+	Updaters []Updater
+	Camera   *Camera
+	Input    *Input
+
+	//Timestep is the fixed duration of one update step in seconds, it must be positive
+	Timestep float64
+	//MaxUpdates limits the update steps run in one frame, the rest of the backlog is dropped, 0 means unlimited
+	MaxUpdates int
+	//MaxFPS caps the frame rate, 0 means unlimited
+	MaxFPS float64
+	//ScreenshotDir is the directory of the screenshots and captured frames
+	ScreenshotDir string
+
+	//offscreen is the default framebuffer of the headless app
+	offscreen *Framebuffer
+	//post processes the world and overlay layers before the UI layer is rendered
+	post *PostChain
+
+	alpha           float64
+	screenshotScale int
+	capture         *capture
 
 	horizontalAngle float64
 	verticalAngle   float64
 	mouseSpeed      float64
-	speed           float64
-	lastTime        float64
 }
 
-//NewApp creates window
-func NewApp(title string, width, height int, cam *Camera) (*App, error) {
+//NewApp creates window and gl context configured by the config
+func NewApp(config WindowConfig, cam *Camera) (*App, error) {
+	config = config.withDefaults()
 	if err := glfw.Init(); err != nil {
 		return nil, fmt.Errorf("failed to initialize glfw: %s", err)
 	}
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-	glfw.WindowHint(glfw.ContextVersionMajor, 4)
-	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.Resizable, glfwBool(config.Resizable && !config.Headless))
+	glfw.WindowHint(glfw.Visible, glfwBool(!config.Headless))
+	glfw.WindowHint(glfw.Samples, config.Samples)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
-	window, err := glfw.CreateWindow(width, height, "Cube", nil, nil)
+	window, err := createWindow(&config)
 	if err != nil {
+		glfw.Terminate()
 		return nil, fmt.Errorf("error creating window: %s", err)
 	}
+	app, err := newApp(window, config, cam)
+	if err != nil {
+		window.Destroy()
+		glfw.Terminate()
+		return nil, err
+	}
+	return app, nil
+}
+
+//newApp initializes gl in the context of the window and creates the app
+func newApp(window *glfw.Window, config WindowConfig, cam *Camera) (*App, error) {
 	window.MakeContextCurrent()
 	// Initialize Glow
 	if err := gl.Init(); err != nil {
 		return nil, fmt.Errorf("error initializing gl: %s", err)
 	}
-	return &App{window: window, Camera: cam}, nil
+	app := &App{
+		window:        window,
+		config:        config,
+		Camera:        cam,
+		Input:         NewInput(),
+		Timestep:      1.0 / 60,
+		MaxUpdates:    5,
+		ScreenshotDir: defaultScreenshotDir,
+	}
+	// Configure global settings
+	gl.Enable(gl.CULL_FACE)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.DepthFunc(gl.LESS)
+	if config.Samples > 0 {
+		gl.Enable(gl.MULTISAMPLE)
+	}
+	if config.Headless {
+		var err error
+		app.offscreen, err = newOffscreen(config.Width, config.Height)
+		if err != nil {
+			return nil, err
+		}
+		app.framebufferSize(window, 0, 0)
+		return app, nil
+	}
+	app.SetVSync(config.VSync)
+	window.SetKeyCallback(app.Input.key)
+	window.SetMouseButtonCallback(app.Input.mouseButton)
+	window.SetFramebufferSizeCallback(app.framebufferSize)
+	app.framebufferSize(window, 0, 0)
+	if config.Fullscreen {
+		if err := app.SetFullscreen(true, config.Monitor); err != nil {
+			return nil, err
+		}
+	}
+	return app, nil
+}
+
+//FramebufferSize returns the size of the default framebuffer in pixels
+func (app *App) FramebufferSize() (width, height int) {
+	if app.offscreen != nil {
+		return app.offscreen.Size()
+	}
+	return app.window.GetFramebufferSize()
 }
 
-//Terminate terminates glfw
+//Terminate destroys the renderers in the reverse order and terminates glfw
 func (app *App) Terminate() {
+	if err := app.StopCapture(); err != nil {
+		log.Println(err)
+	}
+	for l := layerCount - 1; l >= 0; l-- {
+		for i := len(app.layers[l]) - 1; i >= 0; i-- {
+			if d, ok := app.layers[l][i].(Destroyer); ok {
+				d.Destroy()
+			}
+		}
+		app.layers[l] = nil
+	}
+	app.removed = nil
+	if app.post != nil {
+		app.post.Delete()
+	}
+	if app.offscreen != nil {
+		app.offscreen.Delete()
+	}
 	glfw.Terminate()
 }
 
-//Run runs the main loop every time it runs trough renderers
-func (app *App) Run() error {
-	// Configure global settings
-	gl.Enable(gl.CULL_FACE)
-	gl.Enable(gl.DEPTH_TEST)
-	gl.DepthFunc(gl.LESS)
+//AddRenderer initializes the renderer and adds it at the end of the layer
+func (app *App) AddRenderer(layer Layer, r Renderer) error {
+	if i, ok := r.(Initializer); ok {
+		if err := i.Init(app); err != nil {
+			return err
+		}
+	}
+	if rs, ok := r.(Resizer); ok {
+		rs.Resize(app.FramebufferSize())
+	}
+	app.layers[layer] = append(app.layers[layer], r)
+	return nil
+}
+
+//RemoveRenderer removes the renderer from its layer and destroys it at the end of the frame,
+//so the renderers can remove themselves in Update or Render, the renderer must be comparable, e.g. a pointer
+func (app *App) RemoveRenderer(r Renderer) error {
+	if r == nil || !reflect.TypeOf(r).Comparable() {
+		return fmt.Errorf("renderer %T isn't comparable", r)
+	}
+	app.removed = append(app.removed, r)
+	return nil
+}
 
+//removeRenderers removes and destroys the renderers removed during the frame
+func (app *App) removeRenderers() {
+	for _, r := range app.removed {
+		found := false
+		for l := range app.layers {
+			renderers := make([]Renderer, 0, len(app.layers[l]))
+			for _, lr := range app.layers[l] {
+				//r is comparable, so the comparison doesn't panic
+				if lr == r {
+					found = true
+					continue
+				}
+				renderers = append(renderers, lr)
+			}
+			app.layers[l] = renderers
+		}
+		if d, ok := r.(Destroyer); ok && found {
+			d.Destroy()
+		}
+	}
+	app.removed = nil
+}
+
+//framebufferSize updates the viewport and the camera aspect ratio to the framebuffer size,
+//which is in pixels and differs from the window size on HiDPI displays
+func (app *App) framebufferSize(_ *glfw.Window, _, _ int) {
+	width, height := app.FramebufferSize()
+	if width == 0 || height == 0 {
+		//minimized
+		return
+	}
+	gl.Viewport(0, 0, int32(width), int32(height))
+	if app.Camera != nil {
+		app.Camera.SetAspect(float32(width) / float32(height))
+	}
+	app.resize(width, height)
+}
+
+//resize notifies the post-processing chain and the renderers about the new framebuffer size
+func (app *App) resize(width, height int) {
+	if app.post != nil {
+		if err := app.post.Resize(width, height); err != nil {
+			log.Println(err)
+		}
+	}
+	for _, renderers := range app.layers {
+		for _, r := range renderers {
+			if rs, ok := r.(Resizer); ok {
+				rs.Resize(width, height)
+			}
+		}
+	}
+}
+
+//SetVSync enables or disables waiting for the vertical retrace on buffer swap
+func (app *App) SetVSync(on bool) {
+	if on {
+		glfw.SwapInterval(1)
+	} else {
+		glfw.SwapInterval(0)
+	}
+}
+
+//Run runs the main loop, every frame it runs the updaters and the renderers implementing Updater
+//in fixed time steps and then renders the layers with the fraction of the step left in the accumulator
+func (app *App) Run() error {
 	previousTime := glfw.GetTime()
+	var accumulator float64
 
 	for !app.window.ShouldClose() {
+		//the timestep can be changed by the updaters
+		if app.Timestep <= 0 {
+			return fmt.Errorf("timestep %f isn't positive", app.Timestep)
+		}
+		frameStart := glfw.GetTime()
+		elapsed := frameStart - previousTime
+		previousTime = frameStart
+		app.screenshotActions()
 
 		// Update
-		time := glfw.GetTime()
-		elapsed := time - previousTime
-		previousTime = time
-
-		for _, r := range app.Renderers {
-			r.Render(app.Camera.ProjView, elapsed)
+		accumulator += elapsed
+		for updates := 0; accumulator >= app.Timestep; updates++ {
+			if app.MaxUpdates > 0 && updates >= app.MaxUpdates {
+				accumulator = math.Mod(accumulator, app.Timestep)
+				break
+			}
+			app.update()
+			accumulator -= app.Timestep
 		}
 
+		// Render
+		app.render(accumulator / app.Timestep)
+		app.afterRender()
+		app.removeRenderers()
+
 		// Maintenance
 		app.window.SwapBuffers()
 		glfw.PollEvents()
+
+		if app.MaxFPS > 0 {
+			frameTime := glfw.GetTime() - frameStart
+			if wait := 1/app.MaxFPS - frameTime; wait > 0 {
+				time.Sleep(time.Duration(wait * float64(time.Second)))
+			}
+		}
 	}
 	return nil
 }
 
-func (app *App) SetCameraCursor(mouseSpeed, speed float64) {
-	app.mouseSpeed = mouseSpeed
-	app.speed = speed
-	app.lastTime = glfw.GetTime()
-	app.window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
-	app.window.SetCursorPosCallback(app.cursor)
-	app.window.SetKeyCallback(app.key)
+//RenderFrame runs the update steps and renders one frame without swapping the buffers,
+//used with the headless app to render images
+func (app *App) RenderFrame(updates int) {
+	for i := 0; i < updates; i++ {
+		app.update()
+	}
+	app.render(0)
+	app.removeRenderers()
+	gl.Finish()
 }
 
-func (app *App) cursor(_ *glfw.Window, xpos, ypos float64) {
-	w, h := app.window.GetSize()
-	app.window.SetCursorPos(float64(w)/2, float64(h)/2)
-	app.horizontalAngle += app.mouseSpeed * (float64(w)/2 - xpos)
-	app.verticalAngle += app.mouseSpeed * (float64(h)/2 - ypos)
+//update runs one update step
+func (app *App) update() {
+	for _, u := range app.Updaters {
+		u.Update(app.Timestep)
+	}
+	for _, renderers := range app.layers {
+		for _, r := range renderers {
+			if u, ok := r.(Updater); ok {
+				u.Update(app.Timestep)
+			}
+		}
+	}
+	if app.post != nil {
+		app.post.Poll()
+	}
+	app.Input.Advance()
+}
 
-	direction := mgl32.Vec3{
-		float32(math.Cos(app.verticalAngle) * math.Sin(app.horizontalAngle)),
-		float32(math.Sin(app.verticalAngle)),
-		float32(math.Cos(app.verticalAngle) * math.Cos(app.horizontalAngle)),
+//render renders the layers into the default framebuffer, the layers under the UI layer
+//are rendered into the post-processing chain when it has any enabled pass
+func (app *App) render(alpha float64) {
+	app.alpha = alpha
+	post := app.post != nil && app.post.Active() && app.post.fits(app.FramebufferSize())
+	if post {
+		app.post.Begin()
+	} else {
+		app.BindDefaultFramebuffer()
+	}
+	for l, renderers := range app.layers {
+		if post && Layer(l) == UILayer {
+			app.post.Apply(app.BindDefaultFramebuffer)
+		}
+		for _, r := range renderers {
+			r.Render(app.Camera.ProjView, alpha)
+		}
 	}
+}
 
-	right := mgl32.Vec3{
-		float32(math.Sin(app.horizontalAngle - math.Pi/2)),
-		0,
-		float32(math.Cos(app.horizontalAngle - math.Pi/2)),
+//NewPostChain creates the post-processing chain of the framebuffer size and samples and makes it
+//the chain of the app, the shaders are read by pp, the old chain is deleted
+func (app *App) NewPostChain(pp *Preprocessor) (*PostChain, error) {
+	width, height := app.FramebufferSize()
+	samples := app.config.Samples
+	if app.offscreen != nil {
+		samples = 0
 	}
+	post, err := NewPostChain(pp, width, height, samples)
+	if err != nil {
+		return nil, err
+	}
+	if app.post != nil {
+		app.post.Delete()
+	}
+	app.post = post
+	return post, nil
+}
 
-	up := right.Cross(direction)
+//PostChain returns the post-processing chain of the app or nil
+func (app *App) PostChain() *PostChain {
+	return app.post
+}
 
-	app.Camera.Rotation = app.Camera.Pos.Add(direction)
-	app.Camera.Up = up
-	app.Camera.Update()
+//SetCameraCursor captures the cursor and rotates the camera with the mouse
+func (app *App) SetCameraCursor(mouseSpeed float64) {
+	app.mouseSpeed = mouseSpeed
+	app.window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+	app.window.SetCursorPosCallback(app.cursor)
 }
 
-func (app *App) key(_ *glfw.Window, key glfw.Key, scanCode int, action glfw.Action, mods glfw.ModifierKey) {
-	switch key {
-	case glfw.KeyW:
-	case glfw.KeyA:
-	case glfw.KeyS:
-	case glfw.KeyD:
-	default:
-		return
-	}
-	currentTime := glfw.GetTime()
-	deltaTime := currentTime - app.lastTime
+func (app *App) cursor(_ *glfw.Window, xpos, ypos float64) {
+	w, h := app.window.GetSize()
+	app.window.SetCursorPos(float64(w)/2, float64(h)/2)
+	dx, dy := xpos-float64(w)/2, ypos-float64(h)/2
+	app.Input.moveCursor(dx, dy)
+	app.horizontalAngle -= app.mouseSpeed * dx
+	app.verticalAngle -= app.mouseSpeed * dy
 
 	direction := mgl32.Vec3{
 		float32(math.Cos(app.verticalAngle) * math.Sin(app.horizontalAngle)),
@@ -150,22 +380,9 @@ func (app *App) key(_ *glfw.Window, key glfw.Key, scanCode int, action glfw.Acti
 		float32(math.Cos(app.horizontalAngle - math.Pi/2)),
 	}
 
-	switch key {
-	case glfw.KeyW:
-		app.Camera.Pos = app.Camera.Pos.Add(direction.Mul(float32(deltaTime * app.speed)))
-	case glfw.KeyS:
-		app.Camera.Pos = app.Camera.Pos.Sub(direction.Mul(float32(deltaTime * app.speed)))
-	case glfw.KeyA:
-		app.Camera.Pos = app.Camera.Pos.Sub(right.Mul(float32(deltaTime * app.speed)))
-	case glfw.KeyD:
-		app.Camera.Pos = app.Camera.Pos.Add(right.Mul(float32(deltaTime * app.speed)))
-	default:
-		return
-	}
 	up := right.Cross(direction)
+
 	app.Camera.Rotation = app.Camera.Pos.Add(direction)
 	app.Camera.Up = up
 	app.Camera.Update()
-
-	app.lastTime = currentTime
 }
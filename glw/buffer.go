@@ -1,25 +1,140 @@
 package glw
 
-import "github.com/go-gl/gl/v4.5-core/gl"
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
 
 //Buffer the gl data buffer
 type Buffer uint32
 
-//NewBuffer creates new data buffer
+//NewBuffer creates new static vertex buffer with the data, empty data creates an empty buffer
 func NewBuffer(data []float32) Buffer {
+	b, _ := NewBufferData(gl.STATIC_DRAW, data)
+	return b
+}
+
+//NewBufferData creates new buffer with the data and the usage (gl.STATIC_DRAW, gl.DYNAMIC_DRAW, gl.STREAM_DRAW, ...),
+//the data is []byte or a slice of values without pointers, e.g. []float32 or []struct{Pos mgl32.Vec3; Color uint32},
+//the memory of the values is uploaded including the padding
+func NewBufferData(usage uint32, data interface{}) (Buffer, error) {
 	var vbo uint32
 	gl.GenBuffers(1, &vbo)
 	b := Buffer(vbo)
-	b.BindBuffer()
-	gl.BufferData(gl.ARRAY_BUFFER, len(data)*4, gl.Ptr(&data[0]), gl.STATIC_DRAW)
+	if err := b.Data(usage, data); err != nil {
+		b.Delete()
+		return 0, err
+	}
+	return b, nil
+}
+
+//NewBufferSize creates new buffer of the size in bytes with undefined content
+func NewBufferSize(usage uint32, size int) Buffer {
+	var vbo uint32
+	gl.GenBuffers(1, &vbo)
+	b := Buffer(vbo)
+	b.Orphan(usage, size)
 	return b
 }
 
-//BindBuffer ...
+//bufferData returns the pointer to the data and its size in bytes
+func bufferData(data interface{}) (unsafe.Pointer, int, error) {
+	if data == nil {
+		return nil, 0, nil
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return nil, 0, fmt.Errorf("buffer data must be a slice, got %T", data)
+	}
+	elem := v.Type().Elem()
+	if hasPointers(elem) {
+		return nil, 0, fmt.Errorf("buffer data must not contain pointers, got %T", data)
+	}
+	if v.Len() == 0 {
+		return nil, 0, nil
+	}
+	//the memory is uploaded as it is, including the padding of the structs
+	return unsafe.Pointer(v.Pointer()), v.Len() * int(elem.Size()), nil
+}
+
+//hasPointers reports whether the values of the type contain pointers, e.g. strings, slices or maps,
+//which can't be uploaded to the gpu
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.UnsafePointer, reflect.String, reflect.Slice, reflect.Map,
+		reflect.Interface, reflect.Chan, reflect.Func:
+		return true
+	case reflect.Array:
+		return hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//BindBuffer binds the buffer as the vertex buffer
 func (b Buffer) BindBuffer() {
 	gl.BindBuffer(gl.ARRAY_BUFFER, uint32(b))
 }
 
+//Bind binds the buffer to the target, gl.ARRAY_BUFFER, gl.ELEMENT_ARRAY_BUFFER, gl.DRAW_INDIRECT_BUFFER, ...
+func (b Buffer) Bind(target uint32) {
+	gl.BindBuffer(target, uint32(b))
+}
+
+//the updates use the copy target so they don't change the vertex array or other bindings
+const updateTarget = gl.COPY_WRITE_BUFFER
+
+//Data reallocates the buffer with the data and the usage
+func (b Buffer) Data(usage uint32, data interface{}) error {
+	ptr, size, err := bufferData(data)
+	if err != nil {
+		return err
+	}
+	gl.BindBuffer(updateTarget, uint32(b))
+	gl.BufferData(updateTarget, size, ptr, usage)
+	return nil
+}
+
+//SubData replaces the content of the buffer from the offset in bytes with the data
+func (b Buffer) SubData(offset int, data interface{}) error {
+	ptr, size, err := bufferData(data)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	if offset < 0 || offset+size > b.Size() {
+		return fmt.Errorf("buffer update of %d bytes at %d overflows the buffer of %d bytes", size, offset, b.Size())
+	}
+	gl.BindBuffer(updateTarget, uint32(b))
+	gl.BufferSubData(updateTarget, offset, size, ptr)
+	return nil
+}
+
+//Orphan reallocates the buffer of the size with undefined content, so it can be written
+//without waiting for the draw calls still reading the old storage
+func (b Buffer) Orphan(usage uint32, size int) {
+	gl.BindBuffer(updateTarget, uint32(b))
+	gl.BufferData(updateTarget, size, nil, usage)
+}
+
+//Size returns the size of the buffer in bytes
+func (b Buffer) Size() int {
+	var size int32
+	gl.BindBuffer(updateTarget, uint32(b))
+	gl.GetBufferParameteriv(updateTarget, gl.BUFFER_SIZE, &size)
+	return int(size)
+}
+
 //Delete deletes the buffer
 func (b Buffer) Delete() {
 	if b == 0 {
@@ -28,3 +143,111 @@ func (b Buffer) Delete() {
 	vbo := uint32(b)
 	gl.DeleteBuffers(1, &vbo)
 }
+
+//RingBuffer is the persistently mapped buffer split into sections written in turns,
+//every frame writes into the next section while the gpu reads the previous ones,
+//it needs gl 4.4
+type RingBuffer struct {
+	buffer  Buffer
+	data    []byte
+	size    int //size of one section
+	section int
+	offset  int
+	fences  []uintptr
+	//Align is the alignment of the offsets returned by Write,
+	//e.g. gl.UNIFORM_BUFFER_OFFSET_ALIGNMENT for uniform buffers
+	Align int
+}
+
+//NewRingBuffer creates the persistently mapped buffer with the sections of the size in bytes
+func NewRingBuffer(size, sections int) (*RingBuffer, error) {
+	if !glVersion(4, 4) {
+		return nil, fmt.Errorf("ring buffer needs gl 4.4")
+	}
+	if size <= 0 || sections <= 0 {
+		return nil, fmt.Errorf("invalid ring buffer of %d sections of %d bytes", sections, size)
+	}
+	var vbo uint32
+	gl.GenBuffers(1, &vbo)
+	gl.BindBuffer(updateTarget, vbo)
+	const flags = gl.MAP_WRITE_BIT | gl.MAP_PERSISTENT_BIT | gl.MAP_COHERENT_BIT
+	gl.BufferStorage(updateTarget, size*sections, nil, flags)
+	ptr := gl.MapBufferRange(updateTarget, 0, size*sections, flags)
+	if ptr == nil {
+		gl.DeleteBuffers(1, &vbo)
+		return nil, fmt.Errorf("failed to map ring buffer of %d bytes", size*sections)
+	}
+	return &RingBuffer{
+		buffer: Buffer(vbo),
+		data:   (*[1 << 30]byte)(ptr)[: size*sections : size*sections],
+		size:   size,
+		fences: make([]uintptr, sections),
+		Align:  4,
+	}, nil
+}
+
+//glVersion reports whether the context version is at least major.minor
+func glVersion(major, minor int32) bool {
+	var ma, mi int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &ma)
+	gl.GetIntegerv(gl.MINOR_VERSION, &mi)
+	return ma > major || ma == major && mi >= minor
+}
+
+//Buffer returns the buffer to be bound for drawing
+func (rb *RingBuffer) Buffer() Buffer {
+	return rb.buffer
+}
+
+//Begin moves to the next section and waits until the gpu stops reading it
+func (rb *RingBuffer) Begin() {
+	rb.section = (rb.section + 1) % len(rb.fences)
+	rb.offset = 0
+	if fence := rb.fences[rb.section]; fence != 0 {
+		for {
+			status := gl.ClientWaitSync(fence, gl.SYNC_FLUSH_COMMANDS_BIT, 1e9)
+			if status == gl.ALREADY_SIGNALED || status == gl.CONDITION_SATISFIED || status == gl.WAIT_FAILED {
+				break
+			}
+		}
+		gl.DeleteSync(fence)
+		rb.fences[rb.section] = 0
+	}
+}
+
+//Write copies the data into the current section and returns its offset in the buffer in bytes
+func (rb *RingBuffer) Write(data interface{}) (int, error) {
+	ptr, size, err := bufferData(data)
+	if err != nil {
+		return 0, err
+	}
+	offset := alignUp(rb.offset, rb.Align)
+	if offset+size > rb.size {
+		return 0, fmt.Errorf("ring buffer section of %d bytes is full", rb.size)
+	}
+	start := rb.section*rb.size + offset
+	if size > 0 {
+		copy(rb.data[start:start+size], (*[1 << 30]byte)(ptr)[:size:size])
+	}
+	rb.offset = offset + size
+	return start, nil
+}
+
+//End marks the section as used by the draw calls issued since Begin
+func (rb *RingBuffer) End() {
+	rb.fences[rb.section] = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+}
+
+//Delete unmaps and deletes the buffer
+func (rb *RingBuffer) Delete() {
+	for i, fence := range rb.fences {
+		if fence != 0 {
+			gl.DeleteSync(fence)
+			rb.fences[i] = 0
+		}
+	}
+	gl.BindBuffer(updateTarget, uint32(rb.buffer))
+	gl.UnmapBuffer(updateTarget)
+	rb.buffer.Delete()
+	rb.data = nil
+}
@@ -0,0 +1,75 @@
+package glw
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+//Camera the world camera
+type Camera struct {
+	Pos         mgl32.Vec3
+	Rotation    mgl32.Vec3
+	Up          mgl32.Vec3
+	Perspective mgl32.Mat4
+	Model       mgl32.Mat4
+	ProjView    mgl32.Mat4
+
+	FOV    float32 //vertical field of view in radians
+	Aspect float32
+	Near   float32
+	Far    float32
+}
+
+//Update recalculates MVP
+func (cam *Camera) Update() {
+	cam.ProjView = cam.Perspective.Mul4(cam.View()).Mul4(cam.Model)
+}
+
+//View returns the view matrix of the camera
+func (cam *Camera) View() mgl32.Mat4 {
+	return mgl32.LookAtV(cam.Pos, cam.Rotation, cam.Up)
+}
+
+//SetPerspective sets the projection parameters and recalculates MVP
+func (cam *Camera) SetPerspective(fov, aspect, near, far float32) {
+	cam.FOV, cam.Aspect, cam.Near, cam.Far = fov, aspect, near, far
+	cam.Perspective = mgl32.Perspective(fov, aspect, near, far)
+	cam.Update()
+}
+
+//SetAspect changes the aspect ratio of the projection, the cameras without FOV keep their Perspective
+func (cam *Camera) SetAspect(aspect float32) {
+	if cam.FOV == 0 {
+		return
+	}
+	cam.SetPerspective(cam.FOV, aspect, cam.Near, cam.Far)
+}
+
+//CascadeSplits splits the view distance from Near to far into n ranges for the cascaded shadow maps,
+//lambda blends the uniform (0) and the logarithmic (1) split scheme,
+//the returned n+1 distances start with Near and end with far
+func (cam *Camera) CascadeSplits(n int, far, lambda float32) []float32 {
+	splits := make([]float32, n+1)
+	near := float64(cam.Near)
+	for i := 0; i <= n; i++ {
+		t := float64(i) / float64(n)
+		log := near * math.Pow(float64(far)/near, t)
+		uniform := near + (float64(far)-near)*t
+		splits[i] = float32(float64(lambda)*log + (1-float64(lambda))*uniform)
+	}
+	return splits
+}
+
+//FrustumCorners returns the world space corners of the view frustum between the near and far distances,
+//the first 4 corners are on the near plane
+func (cam *Camera) FrustumCorners(near, far float32) [8]mgl32.Vec3 {
+	inv := mgl32.Perspective(cam.FOV, cam.Aspect, near, far).Mul4(cam.View()).Mul4(cam.Model).Inv()
+	var corners [8]mgl32.Vec3
+	for i := range corners {
+		ndc := mgl32.Vec4{float32(i&1)*2 - 1, float32(i>>1&1)*2 - 1, float32(i>>2)*2 - 1, 1}
+		p := inv.Mul4x1(ndc)
+		corners[i] = p.Vec3().Mul(1 / p[3])
+	}
+	return corners
+}
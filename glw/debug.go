@@ -0,0 +1,7 @@
+//go:build debug
+// +build debug
+
+package glw
+
+//debug reports the unknown uniform names as errors
+const debug = true
@@ -0,0 +1,290 @@
+package glw
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+//Attachment describes the storage of a framebuffer attachment
+type Attachment struct {
+	InternalFormat int32  //gl.RGBA8, gl.RGBA16F, gl.DEPTH_COMPONENT24, ...
+	Format         uint32 //pixel format of the texture, gl.RGBA, gl.DEPTH_COMPONENT, ...
+	Type           uint32 //pixel type of the texture, gl.UNSIGNED_BYTE, gl.FLOAT, ...
+	Filter         int32  //min and mag filter of the texture, defaults to gl.LINEAR
+	Renderbuffer   bool   //uses renderbuffer which can't be sampled but can be multisampled
+	Compare        bool   //depth texture is compared to the reference by the shadow samplers
+}
+
+//Common attachments
+var (
+	ColorAttachmentRGBA8   = Attachment{InternalFormat: gl.RGBA8, Format: gl.RGBA, Type: gl.UNSIGNED_BYTE}
+	ColorAttachmentRGBA16F = Attachment{InternalFormat: gl.RGBA16F, Format: gl.RGBA, Type: gl.FLOAT}
+	DepthAttachment        = Attachment{InternalFormat: gl.DEPTH_COMPONENT24, Format: gl.DEPTH_COMPONENT, Type: gl.UNSIGNED_INT}
+	DepthRenderbuffer      = Attachment{InternalFormat: gl.DEPTH_COMPONENT24, Renderbuffer: true}
+	ShadowAttachment       = Attachment{InternalFormat: gl.DEPTH_COMPONENT24, Format: gl.DEPTH_COMPONENT, Type: gl.UNSIGNED_INT, Compare: true}
+)
+
+//FramebufferConfig describes the attachments of a framebuffer
+type FramebufferConfig struct {
+	Width   int
+	Height  int
+	Samples int          //samples of the renderbuffer attachments, textures can't be multisampled
+	Layers  int          //layers of the 2D array textures, 0 creates 2D textures
+	Colors  []Attachment //color attachments, multiple render targets are written in this order
+	Depth   *Attachment  //nil framebuffer has no depth buffer
+}
+
+//Framebuffer is the gl framebuffer object with its attachments
+type Framebuffer struct {
+	id     uint32
+	config FramebufferConfig
+	colors []uint32 //textures or renderbuffers of the color attachments
+	depth  uint32
+}
+
+//NewFramebuffer creates framebuffer with the attachments and checks its completeness
+func NewFramebuffer(config FramebufferConfig) (*Framebuffer, error) {
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("invalid framebuffer size %dx%d", config.Width, config.Height)
+	}
+	for _, a := range config.Colors {
+		if config.Samples > 0 && !a.Renderbuffer {
+			return nil, fmt.Errorf("multisampled framebuffer must use renderbuffer attachments")
+		}
+		if config.Layers > 0 && a.Renderbuffer {
+			return nil, fmt.Errorf("layered framebuffer must use texture attachments")
+		}
+	}
+	if config.Layers > 0 && config.Depth != nil && config.Depth.Renderbuffer {
+		return nil, fmt.Errorf("layered framebuffer must use texture attachments")
+	}
+	fb := &Framebuffer{config: config}
+	gl.GenFramebuffers(1, &fb.id)
+	if err := fb.attach(); err != nil {
+		fb.Delete()
+		return nil, err
+	}
+	return fb, nil
+}
+
+//attach creates the attachments of the configured size
+func (fb *Framebuffer) attach() error {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.id)
+	fb.colors = make([]uint32, len(fb.config.Colors))
+	drawBuffers := make([]uint32, len(fb.config.Colors))
+	for i, a := range fb.config.Colors {
+		attachment := gl.COLOR_ATTACHMENT0 + uint32(i)
+		fb.colors[i] = fb.newAttachment(a, attachment)
+		drawBuffers[i] = attachment
+	}
+	if len(drawBuffers) > 0 {
+		gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+	} else {
+		gl.DrawBuffer(gl.NONE)
+		gl.ReadBuffer(gl.NONE)
+	}
+	if fb.config.Depth != nil {
+		fb.depth = fb.newAttachment(*fb.config.Depth, fb.depthAttachment())
+	}
+	return fb.Check()
+}
+
+func (fb *Framebuffer) depthAttachment() uint32 {
+	if fb.config.Depth.InternalFormat == gl.DEPTH24_STENCIL8 || fb.config.Depth.InternalFormat == gl.DEPTH32F_STENCIL8 {
+		return gl.DEPTH_STENCIL_ATTACHMENT
+	}
+	return gl.DEPTH_ATTACHMENT
+}
+
+func (fb *Framebuffer) newAttachment(a Attachment, attachment uint32) uint32 {
+	w, h := int32(fb.config.Width), int32(fb.config.Height)
+	var id uint32
+	if a.Renderbuffer {
+		gl.GenRenderbuffers(1, &id)
+		gl.BindRenderbuffer(gl.RENDERBUFFER, id)
+		if fb.config.Samples > 0 {
+			gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, int32(fb.config.Samples), uint32(a.InternalFormat), w, h)
+		} else {
+			gl.RenderbufferStorage(gl.RENDERBUFFER, uint32(a.InternalFormat), w, h)
+		}
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, attachment, gl.RENDERBUFFER, id)
+		return id
+	}
+	filter := a.Filter
+	if filter == 0 {
+		filter = gl.LINEAR
+	}
+	target := fb.textureTarget()
+	gl.GenTextures(1, &id)
+	gl.BindTexture(target, id)
+	if fb.config.Layers > 0 {
+		gl.TexImage3D(target, 0, a.InternalFormat, w, h, int32(fb.config.Layers), 0, a.Format, a.Type, nil)
+	} else {
+		gl.TexImage2D(target, 0, a.InternalFormat, w, h, 0, a.Format, a.Type, nil)
+	}
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, filter)
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, filter)
+	if a.Compare {
+		//the samples outside of the shadow map are lit
+		border := [4]float32{1, 1, 1, 1}
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER)
+		gl.TexParameterfv(target, gl.TEXTURE_BORDER_COLOR, &border[0])
+		gl.TexParameteri(target, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+		gl.TexParameteri(target, gl.TEXTURE_COMPARE_FUNC, gl.LEQUAL)
+	} else {
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	}
+	if fb.config.Layers > 0 {
+		//all layers are attached, BindLayer selects one of them
+		gl.FramebufferTexture(gl.FRAMEBUFFER, attachment, id, 0)
+	} else {
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, attachment, target, id, 0)
+	}
+	return id
+}
+
+func (fb *Framebuffer) textureTarget() uint32 {
+	if fb.config.Layers > 0 {
+		return gl.TEXTURE_2D_ARRAY
+	}
+	return gl.TEXTURE_2D
+}
+
+//Check returns descriptive error if the framebuffer is incomplete
+func (fb *Framebuffer) Check() error {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.id)
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	if status == gl.FRAMEBUFFER_COMPLETE {
+		return nil
+	}
+	reason, ok := framebufferStatus[status]
+	if !ok {
+		reason = fmt.Sprintf("unknown status 0x%x", status)
+	}
+	return fmt.Errorf("framebuffer %dx%d is incomplete: %s", fb.config.Width, fb.config.Height, reason)
+}
+
+var framebufferStatus = map[uint32]string{
+	gl.FRAMEBUFFER_UNDEFINED:                     "default framebuffer doesn't exist",
+	gl.FRAMEBUFFER_INCOMPLETE_ATTACHMENT:         "an attachment is incomplete, check its format and size",
+	gl.FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT: "no image is attached",
+	gl.FRAMEBUFFER_INCOMPLETE_DRAW_BUFFER:        "a draw buffer has no attachment",
+	gl.FRAMEBUFFER_INCOMPLETE_READ_BUFFER:        "the read buffer has no attachment",
+	gl.FRAMEBUFFER_UNSUPPORTED:                   "the combination of the attachment formats is not supported",
+	gl.FRAMEBUFFER_INCOMPLETE_MULTISAMPLE:        "the attachments have different number of samples",
+	gl.FRAMEBUFFER_INCOMPLETE_LAYER_TARGETS:      "the layered attachments don't match",
+}
+
+//Bind binds the framebuffer for drawing and reading and sets the viewport to its size
+func (fb *Framebuffer) Bind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.id)
+	gl.Viewport(0, 0, int32(fb.config.Width), int32(fb.config.Height))
+}
+
+//BindLayer binds the framebuffer with one layer of its array textures attached,
+//the layered framebuffer is created with all layers attached for the geometry shaders writing gl_Layer
+func (fb *Framebuffer) BindLayer(layer int) {
+	fb.Bind()
+	for i := range fb.config.Colors {
+		gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0+uint32(i), fb.colors[i], 0, int32(layer))
+	}
+	if fb.config.Depth != nil {
+		gl.FramebufferTextureLayer(gl.FRAMEBUFFER, fb.depthAttachment(), fb.depth, 0, int32(layer))
+	}
+}
+
+//Size returns the size of the framebuffer
+func (fb *Framebuffer) Size() (width, height int) {
+	return fb.config.Width, fb.config.Height
+}
+
+//ColorTexture returns the texture of the color attachment,
+//renderbuffer attachments can't be sampled and return 0
+func (fb *Framebuffer) ColorTexture(i int) Texture {
+	if fb.config.Colors[i].Renderbuffer {
+		return 0
+	}
+	return Texture(fb.colors[i])
+}
+
+//DepthTexture returns the texture of the depth attachment, 0 for renderbuffer or missing depth
+func (fb *Framebuffer) DepthTexture() Texture {
+	if fb.config.Depth == nil || fb.config.Depth.Renderbuffer {
+		return 0
+	}
+	return Texture(fb.depth)
+}
+
+//Resize recreates the attachments with the new size, the content is lost
+func (fb *Framebuffer) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid framebuffer size %dx%d", width, height)
+	}
+	if width == fb.config.Width && height == fb.config.Height {
+		return nil
+	}
+	fb.deleteAttachments()
+	fb.config.Width, fb.config.Height = width, height
+	return fb.attach()
+}
+
+//Blit copies the rectangle of the buffers selected by mask (gl.COLOR_BUFFER_BIT, ...) to dst,
+//nil dst is the default framebuffer of the window with the size of the viewport, filter is gl.NEAREST or gl.LINEAR
+func (fb *Framebuffer) Blit(dst *Framebuffer, mask uint32, filter uint32) {
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, fb.id)
+	var dstWidth, dstHeight int
+	if dst != nil {
+		gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, dst.id)
+		dstWidth, dstHeight = dst.config.Width, dst.config.Height
+	} else {
+		gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, 0)
+		var viewport [4]int32
+		gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+		dstWidth, dstHeight = int(viewport[2]), int(viewport[3])
+	}
+	gl.BlitFramebuffer(
+		0, 0, int32(fb.config.Width), int32(fb.config.Height),
+		0, 0, int32(dstWidth), int32(dstHeight),
+		mask, filter,
+	)
+}
+
+//ReadPixels reads the color attachment into an image with the origin in the top left corner
+func (fb *Framebuffer) ReadPixels(attachment int) *image.RGBA {
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, fb.id)
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0 + uint32(attachment))
+	return readPixels(fb.config.Width, fb.config.Height)
+}
+
+func (fb *Framebuffer) deleteAttachments() {
+	for i, a := range fb.config.Colors {
+		if i >= len(fb.colors) {
+			break
+		}
+		deleteAttachment(a, fb.colors[i])
+	}
+	fb.colors = nil
+	if fb.config.Depth != nil && fb.depth != 0 {
+		deleteAttachment(*fb.config.Depth, fb.depth)
+	}
+	fb.depth = 0
+}
+
+func deleteAttachment(a Attachment, id uint32) {
+	if a.Renderbuffer {
+		gl.DeleteRenderbuffers(1, &id)
+	} else {
+		gl.DeleteTextures(1, &id)
+	}
+}
+
+//Delete deletes the framebuffer and its attachments
+func (fb *Framebuffer) Delete() {
+	fb.deleteAttachments()
+	gl.DeleteFramebuffers(1, &fb.id)
+	fb.id = 0
+}
@@ -0,0 +1,69 @@
+package glw
+
+import "github.com/go-gl/mathgl/mgl32"
+
+//Plane is the plane of points p where Normal.Dot(p) + D = 0, the normal points inside the frustum
+type Plane struct {
+	Normal mgl32.Vec3
+	D      float32
+}
+
+//Distance returns the signed distance of the point from the plane, positive inside
+func (pl Plane) Distance(p mgl32.Vec3) float32 {
+	return pl.Normal.Dot(p) + pl.D
+}
+
+//Frustum is the left, right, bottom, top, near and far plane of the view volume
+type Frustum [6]Plane
+
+//NewFrustum extracts the planes of the frustum from the projection-view matrix
+func NewFrustum(m mgl32.Mat4) Frustum {
+	row := func(i int) mgl32.Vec4 {
+		return mgl32.Vec4{m[i], m[4+i], m[8+i], m[12+i]}
+	}
+	r0, r1, r2, r3 := row(0), row(1), row(2), row(3)
+	var f Frustum
+	for i, v := range [6]mgl32.Vec4{
+		r3.Add(r0), r3.Sub(r0),
+		r3.Add(r1), r3.Sub(r1),
+		r3.Add(r2), r3.Sub(r2),
+	} {
+		n := mgl32.Vec3{v[0], v[1], v[2]}
+		l := n.Len()
+		f[i] = Plane{Normal: n.Mul(1 / l), D: v[3] / l}
+	}
+	return f
+}
+
+//Frustum returns the view frustum of the camera
+func (cam *Camera) Frustum() Frustum {
+	return NewFrustum(cam.ProjView)
+}
+
+//ContainsAABB reports whether the axis aligned box intersects the frustum,
+//boxes near the corners of the frustum may be reported as visible
+func (f Frustum) ContainsAABB(min, max mgl32.Vec3) bool {
+	for _, pl := range f {
+		//the corner of the box furthest along the normal
+		p := min
+		for i := 0; i < 3; i++ {
+			if pl.Normal[i] > 0 {
+				p[i] = max[i]
+			}
+		}
+		if pl.Distance(p) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//ContainsSphere reports whether the sphere intersects the frustum
+func (f Frustum) ContainsSphere(center mgl32.Vec3, radius float32) bool {
+	for _, pl := range f {
+		if pl.Distance(center) < -radius {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package glw
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+//testFrustum looks from the eye at the center with 90° vertical fov, square aspect and the view distance 0.1 to 100,
+//so the side planes are at 45° from the view direction
+func testFrustum(eye, center mgl32.Vec3) Frustum {
+	proj := mgl32.Perspective(mgl32.DegToRad(90), 1, 0.1, 100)
+	view := mgl32.LookAtV(eye, center, mgl32.Vec3{0, 1, 0})
+	return NewFrustum(proj.Mul4(view))
+}
+
+func TestFrustumPlanes(t *testing.T) {
+	f := testFrustum(mgl32.Vec3{}, mgl32.Vec3{0, 0, -1})
+	for i, pl := range f {
+		if math.Abs(float64(pl.Normal.Len())-1) > 1e-5 {
+			t.Errorf("normal of plane %d isn't normalized: %v", i, pl.Normal)
+		}
+	}
+	//the points on the near and far plane
+	for i, p := range map[int]mgl32.Vec3{4: {0, 0, -0.1}, 5: {0, 0, -100}} {
+		if d := f[i].Distance(p); math.Abs(float64(d)) > 1e-3 {
+			t.Errorf("distance of %v from plane %d is %f, want 0", p, i, d)
+		}
+	}
+	if d := f[0].Distance(mgl32.Vec3{0, 0, -10}); math.Abs(float64(d)-10/math.Sqrt2) > 1e-3 {
+		t.Errorf("distance from the left plane is %f, want %f", d, 10/math.Sqrt2)
+	}
+}
+
+func TestFrustumContainsAABB(t *testing.T) {
+	tests := []struct {
+		name     string
+		eye      mgl32.Vec3
+		center   mgl32.Vec3
+		min, max mgl32.Vec3
+		want     bool
+	}{
+		{"inside", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-1, -1, -11}, mgl32.Vec3{1, 1, -9}, true},
+		{"behind", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-1, -1, 9}, mgl32.Vec3{1, 1, 11}, false},
+		{"left", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-15, -1, -11}, mgl32.Vec3{-12, 1, -9}, false},
+		{"above", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-1, 12, -11}, mgl32.Vec3{1, 15, -9}, false},
+		{"beyond far", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-1, -1, -110}, mgl32.Vec3{1, 1, -101}, false},
+		{"straddling right", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{5, -1, -11}, mgl32.Vec3{20, 1, -9}, true},
+		{"straddling near", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-1, -1, -1}, mgl32.Vec3{1, 1, 1}, true},
+		{"straddling far", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-1, -1, -101}, mgl32.Vec3{1, 1, -99}, true},
+		{"containing frustum", mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{-200, -200, -200}, mgl32.Vec3{200, 200, 200}, true},
+		{"look at inside", mgl32.Vec3{10, 5, 10}, mgl32.Vec3{}, mgl32.Vec3{-1, -1, -1}, mgl32.Vec3{1, 1, 1}, true},
+		{"look at behind", mgl32.Vec3{10, 5, 10}, mgl32.Vec3{}, mgl32.Vec3{20, 5, 20}, mgl32.Vec3{22, 7, 22}, false},
+		{"look at straddling", mgl32.Vec3{10, 5, 10}, mgl32.Vec3{}, mgl32.Vec3{9, 4, 9}, mgl32.Vec3{11, 6, 11}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := testFrustum(test.eye, test.center)
+			if got := f.ContainsAABB(test.min, test.max); got != test.want {
+				t.Errorf("ContainsAABB(%v, %v) = %v, want %v", test.min, test.max, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFrustumContainsSphere(t *testing.T) {
+	f := testFrustum(mgl32.Vec3{}, mgl32.Vec3{0, 0, -1})
+	if !f.ContainsSphere(mgl32.Vec3{0, 0, -10}, 1) {
+		t.Error("sphere inside isn't contained")
+	}
+	if f.ContainsSphere(mgl32.Vec3{0, 0, 10}, 1) {
+		t.Error("sphere behind is contained")
+	}
+	//the center is outside the left plane, the sphere reaches over it
+	if !f.ContainsSphere(mgl32.Vec3{-12, 0, -10}, 2) {
+		t.Error("sphere straddling the left plane isn't contained")
+	}
+}
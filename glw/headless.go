@@ -0,0 +1,77 @@
+package glw
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+//newOffscreen creates the framebuffer the headless app renders into instead of the window
+func newOffscreen(width, height int) (*Framebuffer, error) {
+	return NewFramebuffer(FramebufferConfig{
+		Width:  width,
+		Height: height,
+		Colors: []Attachment{{InternalFormat: gl.RGBA8, Renderbuffer: true}},
+		Depth:  &DepthRenderbuffer,
+	})
+}
+
+//Headless reports whether the app renders offscreen
+func (app *App) Headless() bool {
+	return app.offscreen != nil
+}
+
+//BindDefaultFramebuffer binds the window framebuffer, or the offscreen one of the headless app,
+//and sets the viewport to its size, renderers drawing into their own framebuffers use it to restore the render target
+func (app *App) BindDefaultFramebuffer() {
+	if app.offscreen != nil {
+		app.offscreen.Bind()
+		return
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	width, height := app.FramebufferSize()
+	gl.Viewport(0, 0, int32(width), int32(height))
+}
+
+//ReadPixels reads the default framebuffer into an image with the origin in the top left corner
+func (app *App) ReadPixels() *image.RGBA {
+	if app.offscreen != nil {
+		return app.offscreen.ReadPixels(0)
+	}
+	width, height := app.FramebufferSize()
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+	gl.ReadBuffer(gl.BACK)
+	return readPixels(width, height)
+}
+
+//readPixels reads the color attachment of the bound read framebuffer and flips it vertically
+func readPixels(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	row := make([]byte, img.Stride)
+	for y := 0; y < height/2; y++ {
+		top := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		bottom := img.Pix[(height-1-y)*img.Stride : (height-y)*img.Stride]
+		copy(row, top)
+		copy(top, bottom)
+		copy(bottom, row)
+	}
+	return img
+}
+
+//SavePNG encodes the image into png file
+func SavePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot encode %s: %s", path, err)
+	}
+	return f.Close()
+}
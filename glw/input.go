@@ -0,0 +1,237 @@
+package glw
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+//Binding is a key or a mouse button an action is bound to
+type Binding struct {
+	Key    glfw.Key
+	Button glfw.MouseButton
+	Mouse  bool
+}
+
+//KeyBinding returns binding of the key
+func KeyBinding(key glfw.Key) Binding {
+	return Binding{Key: key}
+}
+
+//MouseBinding returns binding of the mouse button
+func MouseBinding(button glfw.MouseButton) Binding {
+	return Binding{Button: button, Mouse: true}
+}
+
+//Input tracks the held keys and mouse buttons and maps them to named actions,
+//the state is polled by the game and the edges are cleared after every update step
+type Input struct {
+	bindings map[string][]Binding
+	down     map[Binding]bool
+	pressed  map[Binding]bool
+	released map[Binding]bool
+
+	mouseDX float64
+	mouseDY float64
+}
+
+//NewInput creates input without any bindings
+func NewInput() *Input {
+	return &Input{
+		bindings: make(map[string][]Binding),
+		down:     make(map[Binding]bool),
+		pressed:  make(map[Binding]bool),
+		released: make(map[Binding]bool),
+	}
+}
+
+//Bind replaces the bindings of the action
+func (in *Input) Bind(action string, bindings ...Binding) {
+	in.bindings[action] = bindings
+}
+
+//Bindings returns the bindings of the action
+func (in *Input) Bindings(action string) []Binding {
+	return in.bindings[action]
+}
+
+//LoadBindings reads the bindings from json file mapping the actions to key names,
+//eg. {"jump": ["Space"], "break": ["MouseLeft"]}, actions missing in the file keep their bindings
+func (in *Input) LoadBindings(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var config map[string][]string
+	if err := json.NewDecoder(f).Decode(&config); err != nil {
+		return fmt.Errorf("cannot decode bindings %s: %s", path, err)
+	}
+	for action, names := range config {
+		bindings := make([]Binding, len(names))
+		for i, name := range names {
+			b, err := ParseBinding(name)
+			if err != nil {
+				return fmt.Errorf("%s: action %s: %s", path, action, err)
+			}
+			bindings[i] = b
+		}
+		in.Bind(action, bindings...)
+	}
+	return nil
+}
+
+//ParseBinding returns the binding of the key or mouse button name
+func ParseBinding(name string) (Binding, error) {
+	if button, ok := mouseButtonNames[strings.ToLower(name)]; ok {
+		return MouseBinding(button), nil
+	}
+	if key, ok := keyNames[strings.ToLower(name)]; ok {
+		return KeyBinding(key), nil
+	}
+	return Binding{}, fmt.Errorf("unknown key %q", name)
+}
+
+//IsDown reports whether any of the action bindings is held
+func (in *Input) IsDown(action string) bool {
+	for _, b := range in.bindings[action] {
+		if in.down[b] {
+			return true
+		}
+	}
+	return false
+}
+
+//JustPressed reports whether any of the action bindings was pressed since the last update
+func (in *Input) JustPressed(action string) bool {
+	for _, b := range in.bindings[action] {
+		if in.pressed[b] {
+			return true
+		}
+	}
+	return false
+}
+
+//ConsumePressed reports whether any of the action bindings was pressed and clears their pressed edges,
+//so the action handled once per frame isn't repeated in the frames without the update step
+func (in *Input) ConsumePressed(action string) bool {
+	pressed := false
+	for _, b := range in.bindings[action] {
+		if in.pressed[b] {
+			pressed = true
+			delete(in.pressed, b)
+		}
+	}
+	return pressed
+}
+
+//JustReleased reports whether any of the action bindings was released since the last update
+func (in *Input) JustReleased(action string) bool {
+	for _, b := range in.bindings[action] {
+		if in.released[b] {
+			return true
+		}
+	}
+	return false
+}
+
+//MouseDelta returns the cursor movement since the last update
+func (in *Input) MouseDelta() (dx, dy float64) {
+	return in.mouseDX, in.mouseDY
+}
+
+//Advance clears the pressed and released edges and the mouse delta
+func (in *Input) Advance() {
+	for b := range in.pressed {
+		delete(in.pressed, b)
+	}
+	for b := range in.released {
+		delete(in.released, b)
+	}
+	in.mouseDX, in.mouseDY = 0, 0
+}
+
+func (in *Input) set(b Binding, action glfw.Action) {
+	switch action {
+	case glfw.Press:
+		in.down[b] = true
+		in.pressed[b] = true
+	case glfw.Release:
+		in.down[b] = false
+		in.released[b] = true
+	}
+}
+
+func (in *Input) key(_ *glfw.Window, key glfw.Key, scanCode int, action glfw.Action, mods glfw.ModifierKey) {
+	in.set(KeyBinding(key), action)
+}
+
+func (in *Input) mouseButton(_ *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	in.set(MouseBinding(button), action)
+}
+
+func (in *Input) moveCursor(dx, dy float64) {
+	in.mouseDX += dx
+	in.mouseDY += dy
+}
+
+var mouseButtonNames = map[string]glfw.MouseButton{
+	"mouseleft":   glfw.MouseButtonLeft,
+	"mouseright":  glfw.MouseButtonRight,
+	"mousemiddle": glfw.MouseButtonMiddle,
+	"mouse4":      glfw.MouseButton4,
+	"mouse5":      glfw.MouseButton5,
+}
+
+var keyNames = map[string]glfw.Key{
+	"space":        glfw.KeySpace,
+	"apostrophe":   glfw.KeyApostrophe,
+	"comma":        glfw.KeyComma,
+	"minus":        glfw.KeyMinus,
+	"period":       glfw.KeyPeriod,
+	"slash":        glfw.KeySlash,
+	"semicolon":    glfw.KeySemicolon,
+	"equal":        glfw.KeyEqual,
+	"leftbracket":  glfw.KeyLeftBracket,
+	"backslash":    glfw.KeyBackslash,
+	"rightbracket": glfw.KeyRightBracket,
+	"graveaccent":  glfw.KeyGraveAccent,
+	"escape":       glfw.KeyEscape,
+	"enter":        glfw.KeyEnter,
+	"tab":          glfw.KeyTab,
+	"backspace":    glfw.KeyBackspace,
+	"insert":       glfw.KeyInsert,
+	"delete":       glfw.KeyDelete,
+	"right":        glfw.KeyRight,
+	"left":         glfw.KeyLeft,
+	"down":         glfw.KeyDown,
+	"up":           glfw.KeyUp,
+	"pageup":       glfw.KeyPageUp,
+	"pagedown":     glfw.KeyPageDown,
+	"home":         glfw.KeyHome,
+	"end":          glfw.KeyEnd,
+	"capslock":     glfw.KeyCapsLock,
+	"leftshift":    glfw.KeyLeftShift,
+	"leftcontrol":  glfw.KeyLeftControl,
+	"leftalt":      glfw.KeyLeftAlt,
+	"leftsuper":    glfw.KeyLeftSuper,
+	"rightshift":   glfw.KeyRightShift,
+	"rightcontrol": glfw.KeyRightControl,
+	"rightalt":     glfw.KeyRightAlt,
+	"rightsuper":   glfw.KeyRightSuper,
+}
+
+func init() {
+	for k := glfw.Key0; k <= glfw.Key9; k++ {
+		keyNames[string(rune('0'+k-glfw.Key0))] = k
+	}
+	for k := glfw.KeyA; k <= glfw.KeyZ; k++ {
+		keyNames[string(rune('a'+k-glfw.KeyA))] = k
+	}
+	for k := glfw.KeyF1; k <= glfw.KeyF12; k++ {
+		keyNames[fmt.Sprintf("f%d", 1+k-glfw.KeyF1)] = k
+	}
+}
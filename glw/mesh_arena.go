@@ -0,0 +1,348 @@
+package glw
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+//MeshArena suballocates the meshes of the same vertex layout in a few big buffers,
+//so the meshes of a page are drawn by one multi draw call without rebinding
+type MeshArena struct {
+	program      Program
+	layout       VertexLayout
+	pageVertices int
+	pages        []*arenaPage
+	indirect     Buffer
+	commands     []drawArraysIndirectCommand
+	first        []int32
+	count        []int32
+	multiDraw    bool //glMultiDrawArraysIndirect is available, gl 4.3
+}
+
+//arenaPage is one buffer of the arena with its vertex array
+type arenaPage struct {
+	buffer   Buffer
+	vao      VertexArray
+	capacity int          //in vertices
+	free     []arenaRange //sorted by the start
+	meshes   map[*ArenaMesh]bool
+}
+
+type arenaRange struct {
+	start int
+	count int
+}
+
+//ArenaMesh is the mesh allocated in the arena
+type ArenaMesh struct {
+	arena    *MeshArena
+	page     *arenaPage
+	first    int
+	count    int
+	capacity int
+}
+
+//drawArraysIndirectCommand is the command read by glMultiDrawArraysIndirect
+type drawArraysIndirectCommand struct {
+	Count         uint32
+	InstanceCount uint32
+	First         uint32
+	BaseInstance  uint32
+}
+
+//NewMeshArena creates the arena of the meshes with the layout drawn by the program,
+//the pages hold pageVertices vertices, bigger meshes get their own page
+func NewMeshArena(p Program, layout VertexLayout, pageVertices int) *MeshArena {
+	return &MeshArena{
+		program:      p,
+		layout:       layout,
+		pageVertices: pageVertices,
+		indirect:     NewBufferSize(gl.STREAM_DRAW, 0),
+		multiDraw:    glVersion(4, 3),
+	}
+}
+
+//SetProgram configures the vertex arrays of the pages for the program, e.g. after the shaders are reloaded
+func (a *MeshArena) SetProgram(p Program) error {
+	a.program = p
+	for _, page := range a.pages {
+		if err := page.vao.SetLayout(p, a.layout, page.buffer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *MeshArena) newPage(capacity int) (*arenaPage, error) {
+	page := &arenaPage{
+		buffer:   NewBufferSize(gl.DYNAMIC_DRAW, capacity*int(a.layout.Stride)),
+		vao:      NewVertexArray(),
+		capacity: capacity,
+		free:     []arenaRange{{0, capacity}},
+		meshes:   make(map[*ArenaMesh]bool),
+	}
+	if err := page.vao.SetLayout(a.program, a.layout, page.buffer); err != nil {
+		page.delete()
+		return nil, err
+	}
+	a.pages = append(a.pages, page)
+	return page, nil
+}
+
+//vertexCount returns the number of the vertices of the data
+func (a *MeshArena) vertexCount(data interface{}) (int, error) {
+	_, size, err := bufferData(data)
+	if err != nil {
+		return 0, err
+	}
+	if size%int(a.layout.Stride) != 0 {
+		return 0, fmt.Errorf("mesh data of %d bytes isn't multiple of the vertex size %d", size, a.layout.Stride)
+	}
+	return size / int(a.layout.Stride), nil
+}
+
+//Alloc allocates the mesh and uploads its vertices
+func (a *MeshArena) Alloc(data interface{}) (*ArenaMesh, error) {
+	count, err := a.vertexCount(data)
+	if err != nil {
+		return nil, err
+	}
+	m := &ArenaMesh{arena: a}
+	if err := m.alloc(count); err != nil {
+		return nil, err
+	}
+	return m, m.upload(data)
+}
+
+//alloc finds the first free range of the pages which fits the count of vertices
+func (m *ArenaMesh) alloc(count int) error {
+	a := m.arena
+	for _, page := range a.pages {
+		if first, ok := page.alloc(count); ok {
+			m.page, m.first, m.count, m.capacity = page, first, count, count
+			page.meshes[m] = true
+			return nil
+		}
+	}
+	capacity := a.pageVertices
+	if count > capacity {
+		capacity = count
+	}
+	page, err := a.newPage(capacity)
+	if err != nil {
+		return err
+	}
+	first, _ := page.alloc(count)
+	m.page, m.first, m.count, m.capacity = page, first, count, count
+	page.meshes[m] = true
+	return nil
+}
+
+func (m *ArenaMesh) upload(data interface{}) error {
+	return m.page.buffer.SubData(m.first*int(m.arena.layout.Stride), data)
+}
+
+//Update replaces the vertices of the mesh, the mesh is moved if it doesn't fit its allocation
+func (m *ArenaMesh) Update(data interface{}) error {
+	count, err := m.arena.vertexCount(data)
+	if err != nil {
+		return err
+	}
+	if m.page == nil || count > m.capacity {
+		m.Free()
+		if err := m.alloc(count); err != nil {
+			return err
+		}
+	}
+	m.count = count
+	return m.upload(data)
+}
+
+//Count returns the number of the vertices of the mesh
+func (m *ArenaMesh) Count() int {
+	return m.count
+}
+
+//Free returns the vertices of the mesh to the arena
+func (m *ArenaMesh) Free() {
+	if m.page == nil {
+		return
+	}
+	m.page.release(m.first, m.capacity)
+	delete(m.page.meshes, m)
+	m.page, m.first, m.count, m.capacity = nil, 0, 0, 0
+}
+
+//alloc takes the count of vertices from the first free range which fits
+func (page *arenaPage) alloc(count int) (int, bool) {
+	for i, r := range page.free {
+		if r.count < count {
+			continue
+		}
+		if r.count == count {
+			page.free = append(page.free[:i], page.free[i+1:]...)
+		} else {
+			page.free[i] = arenaRange{r.start + count, r.count - count}
+		}
+		return r.start, true
+	}
+	return 0, false
+}
+
+//release returns the range to the free list merging it with the neighbouring free ranges
+func (page *arenaPage) release(start, count int) {
+	if count == 0 {
+		return
+	}
+	i := sort.Search(len(page.free), func(i int) bool { return page.free[i].start > start })
+	page.free = append(page.free, arenaRange{})
+	copy(page.free[i+1:], page.free[i:])
+	page.free[i] = arenaRange{start, count}
+	if i+1 < len(page.free) && start+count == page.free[i+1].start {
+		page.free[i].count += page.free[i+1].count
+		page.free = append(page.free[:i+1], page.free[i+2:]...)
+	}
+	if i > 0 && page.free[i-1].start+page.free[i-1].count == start {
+		page.free[i-1].count += page.free[i].count
+		page.free = append(page.free[:i], page.free[i+1:]...)
+	}
+}
+
+func (page *arenaPage) delete() {
+	page.vao.Delete()
+	page.buffer.Delete()
+}
+
+//Fragmentation returns the number of the free ranges of all pages,
+//one per page means the pages are compact
+func (a *MeshArena) Fragmentation() int {
+	n := 0
+	for _, page := range a.pages {
+		n += len(page.free)
+	}
+	return n
+}
+
+//Defragment moves the meshes of every fragmented page to the start of a new buffer
+//so its free space is in one range, the empty pages are deleted
+func (a *MeshArena) Defragment() error {
+	pages := a.pages[:0]
+	for _, page := range a.pages {
+		if len(page.meshes) == 0 {
+			page.delete()
+			continue
+		}
+		pages = append(pages, page)
+		if len(page.free) <= 1 && (len(page.free) == 0 || page.free[0].start+page.free[0].count == page.capacity) {
+			continue
+		}
+		meshes := make([]*ArenaMesh, 0, len(page.meshes))
+		for m := range page.meshes {
+			meshes = append(meshes, m)
+		}
+		sort.Slice(meshes, func(i, j int) bool { return meshes[i].first < meshes[j].first })
+
+		stride := int(a.layout.Stride)
+		buffer := NewBufferSize(gl.DYNAMIC_DRAW, page.capacity*stride)
+		gl.BindBuffer(gl.COPY_READ_BUFFER, uint32(page.buffer))
+		gl.BindBuffer(gl.COPY_WRITE_BUFFER, uint32(buffer))
+		first := 0
+		for _, m := range meshes {
+			if m.count > 0 {
+				gl.CopyBufferSubData(gl.COPY_READ_BUFFER, gl.COPY_WRITE_BUFFER, m.first*stride, first*stride, m.count*stride)
+			}
+			//the unused capacity is dropped
+			m.first, m.capacity = first, m.count
+			first += m.count
+		}
+		page.buffer.Delete()
+		page.buffer = buffer
+		page.free = []arenaRange{{first, page.capacity - first}}
+		if first == page.capacity {
+			page.free = nil
+		}
+		if err := page.vao.SetLayout(a.program, a.layout, page.buffer); err != nil {
+			a.pages = pages
+			return err
+		}
+	}
+	for i := len(pages); i < len(a.pages); i++ {
+		a.pages[i] = nil
+	}
+	a.pages = pages
+	return nil
+}
+
+//Draw draws the meshes with one multi draw call per page,
+//the commands are built into the indirect buffer every call
+func (a *MeshArena) Draw(mode uint32, meshes []*ArenaMesh) {
+	for _, page := range a.pages {
+		a.drawPage(mode, page, meshes)
+	}
+}
+
+//DrawOrdered draws the meshes in the order of the slice, e.g. the blended meshes sorted by the distance,
+//the consecutive meshes of the same page are drawn by one multi draw call
+func (a *MeshArena) DrawOrdered(mode uint32, meshes []*ArenaMesh) {
+	for start := 0; start < len(meshes); {
+		end := start + 1
+		for end < len(meshes) && meshes[end].page == meshes[start].page {
+			end++
+		}
+		if page := meshes[start].page; page != nil {
+			a.drawPage(mode, page, meshes[start:end])
+		}
+		start = end
+	}
+}
+
+//drawPage draws the meshes of the page in their order
+func (a *MeshArena) drawPage(mode uint32, page *arenaPage, meshes []*ArenaMesh) {
+	a.commands = a.commands[:0]
+	a.first = a.first[:0]
+	a.count = a.count[:0]
+	for _, m := range meshes {
+		if m.page != page || m.count == 0 {
+			continue
+		}
+		a.commands = append(a.commands, drawArraysIndirectCommand{Count: uint32(m.count), InstanceCount: 1, First: uint32(m.first)})
+		a.first = append(a.first, int32(m.first))
+		a.count = append(a.count, int32(m.count))
+	}
+	if len(a.commands) == 0 {
+		return
+	}
+	page.vao.BindVertexArray()
+	if !a.multiDraw {
+		gl.MultiDrawArrays(mode, &a.first[0], &a.count[0], int32(len(a.first)))
+		return
+	}
+	a.indirect.Data(gl.STREAM_DRAW, a.commands)
+	a.indirect.Bind(gl.DRAW_INDIRECT_BUFFER)
+	gl.MultiDrawArraysIndirect(mode, nil, int32(len(a.commands)), 0)
+}
+
+//Stats returns the number of the pages and the used and the allocated vertices
+func (a *MeshArena) Stats() (pages, used, capacity int) {
+	for _, page := range a.pages {
+		capacity += page.capacity
+		for m := range page.meshes {
+			used += m.count
+		}
+	}
+	return len(a.pages), used, capacity
+}
+
+//Delete deletes the pages, the meshes can't be used anymore
+func (a *MeshArena) Delete() {
+	for _, page := range a.pages {
+		for m := range page.meshes {
+			m.page = nil
+		}
+		page.delete()
+	}
+	a.pages = nil
+	a.indirect.Delete()
+}
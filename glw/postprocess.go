@@ -0,0 +1,367 @@
+package glw
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+//PostVertexShader is the vertex shader of the full screen triangle drawn by the post-processing steps,
+//it's read by the preprocessor of the chain
+const PostVertexShader = "post_vertex_shader.glsl"
+
+//PostStep is one full screen draw of the pass, its fragment shader reads the output of the previous step
+//from the sampler "source" and the input of the pass from "passInput", "texelSize" is the size
+//of the source texel, all uniforms are optional
+type PostStep struct {
+	Shader string             //fragment shader file
+	Params map[string]float32 //float uniforms of the step, override the params of the pass
+}
+
+//PostPass is the named effect of the post-processing chain made of one or more steps
+type PostPass struct {
+	Name    string
+	Enabled bool
+	Params  map[string]float32 //float uniforms set in all steps which use them
+	Steps   []PostStep
+	//Skip skips the enabled pass at runtime, e.g. the underwater tint above the water, it isn't part of the config
+	Skip bool
+
+	shaders []*ShaderProgram
+}
+
+//PostPassConfig is the saved order, state and parameters of the pass
+type PostPassConfig struct {
+	Name    string             `json:"name"`
+	Enabled bool               `json:"enabled"`
+	Params  map[string]float32 `json:"params,omitempty"`
+}
+
+//PostChain renders the scene into the offscreen framebuffer and applies the enabled passes in order,
+//the steps ping-pong between the intermediate framebuffers and the last one draws into the target
+type PostChain struct {
+	pp      *Preprocessor
+	passes  []*PostPass
+	samples int
+	//scene is multisampled when the samples are set, it's resolved into the resolved framebuffer
+	scene    *Framebuffer
+	resolved *Framebuffer
+	buffers  [3]*Framebuffer
+	vao      VertexArray
+}
+
+var (
+	sceneAttachment = Attachment{InternalFormat: gl.RGBA16F, Renderbuffer: true}
+	postDepth       = Attachment{InternalFormat: gl.DEPTH_COMPONENT24, Renderbuffer: true}
+)
+
+//NewPostChain creates the chain with the framebuffers of the size, the shaders are read by pp,
+//nil pp reads them from the DefaultShaderRoot
+func NewPostChain(pp *Preprocessor, width, height, samples int) (*PostChain, error) {
+	if pp == nil {
+		pp = NewPreprocessor(DefaultShaderRoot)
+	}
+	c := &PostChain{pp: pp, samples: samples, vao: NewVertexArray()}
+	if err := c.createFramebuffers(width, height); err != nil {
+		c.Delete()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *PostChain) createFramebuffers(width, height int) error {
+	var err error
+	if c.samples > 0 {
+		c.scene, err = NewFramebuffer(FramebufferConfig{
+			Width:   width,
+			Height:  height,
+			Samples: c.samples,
+			Colors:  []Attachment{sceneAttachment},
+			Depth:   &postDepth,
+		})
+		if err != nil {
+			return err
+		}
+		c.resolved, err = NewFramebuffer(FramebufferConfig{Width: width, Height: height, Colors: []Attachment{ColorAttachmentRGBA16F}})
+		if err != nil {
+			return err
+		}
+	} else {
+		c.scene, err = NewFramebuffer(FramebufferConfig{
+			Width:  width,
+			Height: height,
+			Colors: []Attachment{ColorAttachmentRGBA16F},
+			Depth:  &postDepth,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.buffers {
+		c.buffers[i], err = NewFramebuffer(FramebufferConfig{Width: width, Height: height, Colors: []Attachment{ColorAttachmentRGBA16F}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *PostChain) deleteFramebuffers() {
+	for _, fb := range append([]*Framebuffer{c.scene, c.resolved}, c.buffers[:]...) {
+		if fb != nil {
+			fb.Delete()
+		}
+	}
+	c.scene, c.resolved = nil, nil
+	c.buffers = [3]*Framebuffer{}
+}
+
+//Add compiles the steps of the pass and appends it to the chain
+func (c *PostChain) Add(pass *PostPass) error {
+	if c.Pass(pass.Name) != nil {
+		return fmt.Errorf("post pass %s already exists", pass.Name)
+	}
+	for _, step := range pass.Steps {
+		shader, err := NewShaderProgram(c.pp,
+			ShaderFile{Type: gl.VERTEX_SHADER, Path: PostVertexShader},
+			ShaderFile{Type: gl.FRAGMENT_SHADER, Path: step.Shader},
+		)
+		if err != nil {
+			pass.delete()
+			return fmt.Errorf("post pass %s: %s", pass.Name, err)
+		}
+		pass.shaders = append(pass.shaders, shader)
+	}
+	if pass.Params == nil {
+		pass.Params = make(map[string]float32)
+	}
+	c.passes = append(c.passes, pass)
+	return nil
+}
+
+//active reports whether the pass is applied
+func (pass *PostPass) active() bool {
+	return pass.Enabled && !pass.Skip
+}
+
+func (pass *PostPass) delete() {
+	for _, shader := range pass.shaders {
+		shader.Delete()
+	}
+	pass.shaders = nil
+}
+
+//Pass returns the pass of the name or nil
+func (c *PostChain) Pass(name string) *PostPass {
+	for _, pass := range c.passes {
+		if pass.Name == name {
+			return pass
+		}
+	}
+	return nil
+}
+
+//Passes returns the passes in the order they are applied
+func (c *PostChain) Passes() []*PostPass {
+	return c.passes
+}
+
+//Move moves the pass to the index of the order
+func (c *PostChain) Move(name string, index int) error {
+	if index < 0 || index >= len(c.passes) {
+		return fmt.Errorf("post pass index %d out of range 0-%d", index, len(c.passes)-1)
+	}
+	for i, pass := range c.passes {
+		if pass.Name != name {
+			continue
+		}
+		c.passes = append(c.passes[:i], c.passes[i+1:]...)
+		c.passes = append(c.passes[:index], append([]*PostPass{pass}, c.passes[index:]...)...)
+		return nil
+	}
+	return fmt.Errorf("unknown post pass %s", name)
+}
+
+//Configure orders the passes by the configs and sets their state and parameters,
+//the passes missing in the configs keep their state and follow the configured ones
+func (c *PostChain) Configure(configs []PostPassConfig) error {
+	for i, config := range configs {
+		pass := c.Pass(config.Name)
+		if pass == nil {
+			return fmt.Errorf("unknown post pass %s", config.Name)
+		}
+		if err := c.Move(config.Name, i); err != nil {
+			return err
+		}
+		pass.Enabled = config.Enabled
+		for name, v := range config.Params {
+			pass.Params[name] = v
+		}
+	}
+	return nil
+}
+
+//Config returns the configs of the passes in their order
+func (c *PostChain) Config() []PostPassConfig {
+	configs := make([]PostPassConfig, len(c.passes))
+	for i, pass := range c.passes {
+		params := make(map[string]float32, len(pass.Params))
+		for name, v := range pass.Params {
+			params[name] = v
+		}
+		configs[i] = PostPassConfig{Name: pass.Name, Enabled: pass.Enabled, Params: params}
+	}
+	return configs
+}
+
+//Active reports whether any pass is enabled and not skipped, the scene is rendered directly into the target otherwise
+func (c *PostChain) Active() bool {
+	for _, pass := range c.passes {
+		if pass.active() {
+			return true
+		}
+	}
+	return false
+}
+
+//Begin binds the scene framebuffer for rendering
+func (c *PostChain) Begin() {
+	c.scene.Bind()
+}
+
+//Apply draws the enabled passes which aren't skipped, the last step draws into the framebuffer bound by bindTarget
+func (c *PostChain) Apply(bindTarget func()) {
+	input := c.scene
+	if c.resolved != nil {
+		c.scene.Blit(c.resolved, gl.COLOR_BUFFER_BIT, gl.NEAREST)
+		input = c.resolved
+	}
+	steps := 0
+	for _, pass := range c.passes {
+		if pass.active() {
+			steps += len(pass.Steps)
+		}
+	}
+	gl.Disable(gl.DEPTH_TEST)
+	c.vao.BindVertexArray()
+	source := input
+	for _, pass := range c.passes {
+		if !pass.active() {
+			continue
+		}
+		passInput := source
+		for i, shader := range pass.shaders {
+			steps--
+			var target *Framebuffer
+			if steps == 0 {
+				bindTarget()
+			} else {
+				target = c.buffer(source, passInput)
+				target.Bind()
+			}
+			c.drawStep(pass, pass.Steps[i], shader.Program(), source, passInput)
+			source = target
+		}
+	}
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+//buffer returns the intermediate framebuffer which isn't read by the step
+func (c *PostChain) buffer(source, passInput *Framebuffer) *Framebuffer {
+	for _, fb := range c.buffers {
+		if fb != source && fb != passInput {
+			return fb
+		}
+	}
+	return nil
+}
+
+func (c *PostChain) drawStep(pass *PostPass, step PostStep, p Program, source, passInput *Framebuffer) {
+	p.UseProgram()
+	source.ColorTexture(0).BindToUnit(0)
+	passInput.ColorTexture(0).BindToUnit(1)
+	width, height := source.Size()
+	var err error
+	set := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	if _, ok := p.Uniform("source"); ok {
+		set(p.SetSampler("source", 0))
+	}
+	if _, ok := p.Uniform("passInput"); ok {
+		set(p.SetSampler("passInput", 1))
+	}
+	if _, ok := p.Uniform("texelSize"); ok {
+		set(p.SetVec2("texelSize", mgl32.Vec2{1 / float32(width), 1 / float32(height)}))
+	}
+	//the params of the step are set after the pass ones to override them
+	for _, params := range []map[string]float32{pass.Params, step.Params} {
+		for name, v := range params {
+			if _, ok := p.Uniform(name); ok {
+				set(p.SetFloat(name, v))
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("post pass %s: %s", pass.Name, err)
+	}
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+}
+
+//Resize recreates the framebuffers with the new size, the old ones are kept if it fails
+//and the scene is rendered directly into the target until the chain fits it
+func (c *PostChain) Resize(width, height int) error {
+	if c.fits(width, height) {
+		return nil
+	}
+	resized, err := c.sized(width, height, c.samples)
+	if err != nil {
+		return err
+	}
+	c.deleteFramebuffers()
+	c.scene, c.resolved, c.buffers = resized.scene, resized.resolved, resized.buffers
+	return nil
+}
+
+//sized returns the chain sharing the passes and the vertex array with c with its own framebuffers of the size,
+//only its framebuffers may be deleted
+func (c *PostChain) sized(width, height, samples int) (*PostChain, error) {
+	sized := &PostChain{pp: c.pp, passes: c.passes, samples: samples, vao: c.vao}
+	if err := sized.createFramebuffers(width, height); err != nil {
+		sized.deleteFramebuffers()
+		return nil, err
+	}
+	return sized, nil
+}
+
+//fits reports whether the framebuffers of the chain have the size
+func (c *PostChain) fits(width, height int) bool {
+	if c.scene == nil {
+		return false
+	}
+	w, h := c.scene.Size()
+	return w == width && h == height
+}
+
+//Poll reloads the shaders of the passes when they change
+func (c *PostChain) Poll() {
+	for _, pass := range c.passes {
+		for _, shader := range pass.shaders {
+			shader.Poll()
+		}
+	}
+}
+
+//Delete deletes the framebuffers and the programs of the passes
+func (c *PostChain) Delete() {
+	for _, pass := range c.passes {
+		pass.delete()
+	}
+	c.passes = nil
+	c.deleteFramebuffers()
+	c.vao.Delete()
+}
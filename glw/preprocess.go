@@ -0,0 +1,124 @@
+package glw
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//DefaultShaderRoot is the directory of the shader files
+const DefaultShaderRoot = "assets/shaders"
+
+var (
+	includeDirective = regexp.MustCompile(`^\s*#include\s+"([^"]+)"\s*$`)
+	versionDirective = regexp.MustCompile(`^\s*#version\b`)
+)
+
+//Preprocessor resolves the #include "file.glsl" directives and injects the defines into the shader sources,
+//the #line directives are emitted so the compile errors point to the original files
+type Preprocessor struct {
+	//Root is the directory of the shader files and the included files
+	Root string
+	//Defines are injected after the #version directive as #define name value
+	Defines map[string]string
+}
+
+//NewPreprocessor creates preprocessor of the files in the root directory
+func NewPreprocessor(root string) *Preprocessor {
+	return &Preprocessor{Root: root, Defines: make(map[string]string)}
+}
+
+//SetDefine sets the define, the empty value defines just the name for #ifdef
+func (pp *Preprocessor) SetDefine(name, value string) {
+	if pp.Defines == nil {
+		pp.Defines = make(map[string]string)
+	}
+	pp.Defines[name] = value
+}
+
+//Undefine removes the define
+func (pp *Preprocessor) Undefine(name string) {
+	delete(pp.Defines, name)
+}
+
+//Process reads the file relative to the root and returns the shader source and the paths of all read files,
+//the index of the path is the source string number used in the #line directives,
+//on error the paths include the file that failed to open
+func (pp *Preprocessor) Process(path string) (string, []string, error) {
+	var b strings.Builder
+	var files []string
+	if err := pp.include(&b, &files, path, nil); err != nil {
+		return "", files, err
+	}
+	return b.String(), files, nil
+}
+
+//include writes the file into b, the included files are inserted only once
+func (pp *Preprocessor) include(b *strings.Builder, files *[]string, path string, stack []string) error {
+	for _, f := range stack {
+		if f == path {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), path)
+		}
+	}
+	for _, f := range *files {
+		if f == path {
+			return nil
+		}
+	}
+	index := len(*files)
+	*files = append(*files, path)
+	data, err := ioutil.ReadFile(filepath.Join(pp.Root, path))
+	if err != nil {
+		return err
+	}
+	stack = append(stack, path)
+
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	start := 0
+	if len(stack) == 1 {
+		//the #version must be the first directive, the defines go right after it
+		for i, line := range lines {
+			if versionDirective.MatchString(line) {
+				for _, l := range lines[:i+1] {
+					b.WriteString(l + "\n")
+				}
+				start = i + 1
+				break
+			}
+		}
+		pp.writeDefines(b)
+		fmt.Fprintf(b, "#line %d %d\n", start+1, index)
+	} else {
+		fmt.Fprintf(b, "#line 1 %d\n", index)
+	}
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if versionDirective.MatchString(line) && len(stack) > 1 {
+			return fmt.Errorf("%s:%d: #version in included file", path, i+1)
+		}
+		m := includeDirective.FindStringSubmatch(line)
+		if m == nil {
+			b.WriteString(line + "\n")
+			continue
+		}
+		if err := pp.include(b, files, m[1], stack); err != nil {
+			return fmt.Errorf("%s:%d: %s", path, i+1, err)
+		}
+		fmt.Fprintf(b, "#line %d %d\n", i+2, index)
+	}
+	return nil
+}
+
+func (pp *Preprocessor) writeDefines(b *strings.Builder) {
+	names := make([]string, 0, len(pp.Defines))
+	for name := range pp.Defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "#define %s %s\n", name, pp.Defines[name])
+	}
+}
@@ -0,0 +1,141 @@
+package glw
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+//newTestPreprocessor writes the files into a temporary root directory
+func newTestPreprocessor(t *testing.T, files map[string]string) *Preprocessor {
+	root, err := ioutil.TempDir("", "shaders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	for path, source := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, path), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewPreprocessor(root)
+}
+
+func TestPreprocessorLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		defines map[string]string
+		source  string
+		read    []string
+	}{
+		{
+			name:   "no version",
+			files:  map[string]string{"main.glsl": "a\nb"},
+			source: "#line 1 0\na\nb\n",
+			read:   []string{"main.glsl"},
+		},
+		{
+			name:    "defines after version",
+			files:   map[string]string{"main.glsl": "// comment\n#version 450 core\nmain"},
+			defines: map[string]string{"B": "", "A": "2"},
+			source:  "// comment\n#version 450 core\n#define A 2\n#define B \n#line 3 0\nmain\n",
+			read:    []string{"main.glsl"},
+		},
+		{
+			name: "include",
+			files: map[string]string{
+				"main.glsl":   "#version 450\n#include \"common.glsl\"\nmain",
+				"common.glsl": "c1\r\nc2",
+			},
+			source: "#version 450\n#line 2 0\n#line 1 1\nc1\nc2\n#line 3 0\nmain\n",
+			read:   []string{"main.glsl", "common.glsl"},
+		},
+		{
+			name: "nested include",
+			files: map[string]string{
+				"main.glsl": "#version 450\n#include \"a.glsl\"\nmain",
+				"a.glsl":    "a1\n  #include \"b.glsl\"  \na3",
+				"b.glsl":    "b1",
+			},
+			source: "#version 450\n#line 2 0\n#line 1 1\na1\n#line 1 2\nb1\n#line 3 1\na3\n#line 3 0\nmain\n",
+			read:   []string{"main.glsl", "a.glsl", "b.glsl"},
+		},
+		{
+			name: "included once",
+			files: map[string]string{
+				"main.glsl": "#version 450\n#include \"a.glsl\"\n#include \"a.glsl\"\nmain",
+				"a.glsl":    "a1",
+			},
+			source: "#version 450\n#line 2 0\n#line 1 1\na1\n#line 3 0\n#line 4 0\nmain\n",
+			read:   []string{"main.glsl", "a.glsl"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pp := newTestPreprocessor(t, test.files)
+			for name, value := range test.defines {
+				pp.SetDefine(name, value)
+			}
+			source, read, err := pp.Process("main.glsl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if source != test.source {
+				t.Errorf("source:\n%s\nwant:\n%s", source, test.source)
+			}
+			if !reflect.DeepEqual(read, test.read) {
+				t.Errorf("read files %v, want %v", read, test.read)
+			}
+		})
+	}
+}
+
+func TestPreprocessorErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		read  []string
+	}{
+		{
+			name:  "missing file",
+			files: map[string]string{},
+			read:  []string{"main.glsl"},
+		},
+		{
+			name:  "missing include",
+			files: map[string]string{"main.glsl": "#version 450\n#include \"missing.glsl\""},
+			read:  []string{"main.glsl", "missing.glsl"},
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"main.glsl": "#version 450\n#include \"a.glsl\"",
+				"a.glsl":    "#include \"main.glsl\"",
+			},
+			read: []string{"main.glsl", "a.glsl"},
+		},
+		{
+			name: "version in include",
+			files: map[string]string{
+				"main.glsl": "#version 450\n#include \"a.glsl\"",
+				"a.glsl":    "#version 450",
+			},
+			read: []string{"main.glsl", "a.glsl"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pp := newTestPreprocessor(t, test.files)
+			_, read, err := pp.Process("main.glsl")
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !reflect.DeepEqual(read, test.read) {
+				t.Errorf("read files %v, want %v", read, test.read)
+			}
+		})
+	}
+}
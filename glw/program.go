@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
@@ -17,25 +20,41 @@ type Program uint32
 type ShaderSource struct {
 	Type   uint32
 	Source io.Reader
+	Name   string   //file name used in the compile errors
+	Files  []string //file names of the source string numbers in the #line directives, replace the Name
 }
 
 //NewProgram reads an compiles shaders and links new program
 func NewProgram(shaderSources ...ShaderSource) (Program, error) {
 	sources := make([]string, len(shaderSources))
+	names := make([]string, len(shaderSources))
 	for i, s := range shaderSources {
+		names[i] = s.Name
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("shader %d", i)
+		}
 		source, err := ioutil.ReadAll(s.Source)
 		if err != nil {
-			return 0, fmt.Errorf("cannot read shader %d: %s", i, err)
+			return 0, fmt.Errorf("cannot read %s: %s", names[i], err)
 		}
 		sources[i] = string(source) + "\x00"
 	}
-	shaders := make([]uint32, len(shaderSources))
+	shaders := make([]uint32, 0, len(shaderSources))
+	defer func() {
+		for _, s := range shaders {
+			gl.DeleteShader(s)
+		}
+	}()
 	for i := 0; i < len(shaderSources); i++ {
 		shader, err := compileShader(sources[i], shaderSources[i].Type)
 		if err != nil {
-			return 0, fmt.Errorf("shader %d compile error: %s", i, err)
+			files := shaderSources[i].Files
+			if len(files) == 0 {
+				files = []string{names[i]}
+			}
+			return 0, fmt.Errorf("%s compile error: %s", names[i], formatShaderLog(err.Error(), files))
 		}
-		shaders[i] = shader
+		shaders = append(shaders, shader)
 	}
 	program := gl.CreateProgram()
 	for _, s := range shaders {
@@ -44,18 +63,38 @@ func NewProgram(shaderSources ...ShaderSource) (Program, error) {
 
 	gl.LinkProgram(program)
 	if err := getShaderError(program, gl.LINK_STATUS); err != nil {
-		return 0, err
-	}
-	gl.ValidateProgram(program)
-	if err := getShaderError(program, gl.VALIDATE_STATUS); err != nil {
-		return 0, err
+		gl.DeleteProgram(program)
+		return 0, fmt.Errorf("%s: %s", strings.Join(names, ", "), err)
 	}
+	programs[Program(program)] = Program(program).reflect()
+	return Program(program), nil
+}
 
-	for _, s := range shaders {
-		gl.DeleteShader(s)
+//Validate checks whether the program can be executed in the current gl state,
+//e.g. the samplers of different types mustn't use the same texture unit, so it's called after the samplers are set
+func (p Program) Validate() error {
+	gl.ValidateProgram(uint32(p))
+	if err := getShaderError(uint32(p), gl.VALIDATE_STATUS); err != nil {
+		return fmt.Errorf("program %d: %s", p, err)
 	}
+	return nil
+}
 
-	return Program(program), nil
+//shaderLogLocation matches the source string number and the line at the start of the log lines
+//in the formats of the common drivers: "0(12) : error", "0:12(5): error" and "ERROR: 0:12: "
+var shaderLogLocation = regexp.MustCompile(`(?m)^(ERROR: |WARNING: )?(\d+)[:(](\d+)\)?`)
+
+//formatShaderLog replaces the source string numbers in the shader info log with the file names,
+//so the errors read as "file.glsl:12: error ..."
+func formatShaderLog(log string, names []string) string {
+	return shaderLogLocation.ReplaceAllStringFunc(log, func(loc string) string {
+		m := shaderLogLocation.FindStringSubmatch(loc)
+		i, _ := strconv.Atoi(m[2])
+		if i >= len(names) {
+			return loc
+		}
+		return m[1] + names[i] + ":" + m[3]
+	})
 }
 
 func getShaderError(program uint32, pname uint32) error {
@@ -66,7 +105,11 @@ func getShaderError(program uint32, pname uint32) error {
 		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
 		log := make([]byte, logLength+1)
 		gl.GetProgramInfoLog(program, logLength, nil, &log[0])
-		return fmt.Errorf("failed to link program: %v", string(log))
+		action := "link"
+		if pname == gl.VALIDATE_STATUS {
+			action = "validate"
+		}
+		return fmt.Errorf("failed to %s program: %v", action, strings.TrimRight(string(log), "\x00"))
 	}
 	return nil
 }
@@ -86,7 +129,8 @@ func compileShader(source string, shaderType uint32) (uint32, error) {
 		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
 		log := make([]byte, logLength+1)
 		gl.GetShaderInfoLog(shader, logLength, nil, &log[0])
-		return 0, fmt.Errorf("failed to compile: %v", string(log))
+		gl.DeleteShader(shader)
+		return 0, fmt.Errorf("%v", strings.TrimRight(string(log), "\x00"))
 	}
 
 	return shader, nil
@@ -114,6 +158,7 @@ func (p Program) BindFragDataLocation(color uint32, name string) {
 
 //Delete deletes the program
 func (p Program) Delete() {
+	delete(programs, p)
 	gl.DeleteProgram(uint32(p))
 }
 
@@ -147,3 +192,8 @@ func (ul UniformLocation) UniformMatrix4fv(count int32, transpose bool, m mgl32.
 func (ul UniformLocation) Uniform1i(v int32) {
 	gl.Uniform1i(int32(ul), v)
 }
+
+//Uniform4f ...
+func (ul UniformLocation) Uniform4f(v0, v1, v2, v3 float32) {
+	gl.Uniform4f(int32(ul), v0, v1, v2, v3)
+}
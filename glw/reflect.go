@@ -0,0 +1,393 @@
+package glw
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+//UniformInfo describes an active uniform of the program
+type UniformInfo struct {
+	Name     string
+	Type     uint32 //gl.FLOAT_VEC3, gl.SAMPLER_2D, ...
+	Size     int32  //length of the array, 1 for non-array uniforms
+	Location UniformLocation
+}
+
+//AttribInfo describes an active vertex attribute of the program
+type AttribInfo struct {
+	Name     string
+	Type     uint32
+	Size     int32
+	Location VertexAttrib
+}
+
+//programInfo is the reflected interface of the linked program
+type programInfo struct {
+	uniforms map[string]UniformInfo
+	attribs  map[string]AttribInfo
+}
+
+//programs caches the reflected programs, the entries are removed by Program.Delete
+var programs = make(map[Program]*programInfo)
+
+//info returns the cached interface of the program, it's reflected on first use
+func (p Program) info() *programInfo {
+	if info, ok := programs[p]; ok {
+		return info
+	}
+	info := p.reflect()
+	programs[p] = info
+	return info
+}
+
+//reflect queries the active uniforms and attributes of the program,
+//uniforms in the uniform blocks and built-in attributes have no location and are skipped
+func (p Program) reflect() *programInfo {
+	info := &programInfo{
+		uniforms: make(map[string]UniformInfo),
+		attribs:  make(map[string]AttribInfo),
+	}
+	var count, maxLength int32
+	gl.GetProgramiv(uint32(p), gl.ACTIVE_UNIFORMS, &count)
+	gl.GetProgramiv(uint32(p), gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+	buf := make([]byte, maxLength+1)
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveUniform(uint32(p), uint32(i), int32(len(buf)), &length, &size, &xtype, &buf[0])
+		name := string(buf[:length])
+		location := p.GetUniformLocation(name)
+		if location < 0 {
+			continue
+		}
+		u := UniformInfo{Name: name, Type: xtype, Size: size, Location: location}
+		info.uniforms[name] = u
+		//arrays are reported as name[0] and are set by the name without the index
+		if strings.HasSuffix(name, "[0]") {
+			u.Name = strings.TrimSuffix(name, "[0]")
+			info.uniforms[u.Name] = u
+		}
+	}
+	gl.GetProgramiv(uint32(p), gl.ACTIVE_ATTRIBUTES, &count)
+	gl.GetProgramiv(uint32(p), gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, &maxLength)
+	buf = make([]byte, maxLength+1)
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveAttrib(uint32(p), uint32(i), int32(len(buf)), &length, &size, &xtype, &buf[0])
+		name := string(buf[:length])
+		location := gl.GetAttribLocation(uint32(p), gl.Str(name+"\x00"))
+		if location < 0 {
+			continue
+		}
+		info.attribs[name] = AttribInfo{Name: name, Type: xtype, Size: size, Location: VertexAttrib(location)}
+	}
+	return info
+}
+
+//Uniforms returns the active uniforms sorted by name
+func (p Program) Uniforms() []UniformInfo {
+	uniforms := make([]UniformInfo, 0, len(p.info().uniforms))
+	for name, u := range p.info().uniforms {
+		if name == u.Name {
+			uniforms = append(uniforms, u)
+		}
+	}
+	sort.Slice(uniforms, func(i, j int) bool { return uniforms[i].Name < uniforms[j].Name })
+	return uniforms
+}
+
+//Attribs returns the active vertex attributes sorted by name
+func (p Program) Attribs() []AttribInfo {
+	attribs := make([]AttribInfo, 0, len(p.info().attribs))
+	for _, a := range p.info().attribs {
+		attribs = append(attribs, a)
+	}
+	sort.Slice(attribs, func(i, j int) bool { return attribs[i].Name < attribs[j].Name })
+	return attribs
+}
+
+//Uniform returns the active uniform with the name
+func (p Program) Uniform(name string) (UniformInfo, bool) {
+	u, ok := p.info().uniforms[name]
+	return u, ok
+}
+
+//Attrib returns the active vertex attribute with the name
+func (p Program) Attrib(name string) (AttribInfo, bool) {
+	a, ok := p.info().attribs[name]
+	return a, ok
+}
+
+//Attribute returns the location of the vertex attribute, unknown name is an error also in release builds,
+//unlike the unknown uniform location there's no attribute location ignored by gl
+func (p Program) Attribute(name string) (VertexAttrib, error) {
+	a, ok := p.info().attribs[name]
+	if !ok {
+		return 0, fmt.Errorf("program %d has no active attribute %s", p, name)
+	}
+	return a.Location, nil
+}
+
+//samplerTypes are the types set by SetSampler
+var samplerTypes = []uint32{
+	gl.SAMPLER_1D, gl.SAMPLER_2D, gl.SAMPLER_3D, gl.SAMPLER_CUBE,
+	gl.SAMPLER_2D_SHADOW, gl.SAMPLER_2D_ARRAY, gl.SAMPLER_2D_ARRAY_SHADOW, gl.SAMPLER_2D_MULTISAMPLE,
+	gl.SAMPLER_BUFFER, gl.SAMPLER_CUBE_SHADOW,
+	gl.INT_SAMPLER_2D, gl.INT_SAMPLER_3D, gl.INT_SAMPLER_2D_ARRAY,
+	gl.UNSIGNED_INT_SAMPLER_2D, gl.UNSIGNED_INT_SAMPLER_3D, gl.UNSIGNED_INT_SAMPLER_2D_ARRAY,
+}
+
+var uniformTypeNames = map[uint32]string{
+	gl.FLOAT:             "float",
+	gl.FLOAT_VEC2:        "vec2",
+	gl.FLOAT_VEC3:        "vec3",
+	gl.FLOAT_VEC4:        "vec4",
+	gl.INT:               "int",
+	gl.INT_VEC2:          "ivec2",
+	gl.INT_VEC3:          "ivec3",
+	gl.INT_VEC4:          "ivec4",
+	gl.UNSIGNED_INT:      "uint",
+	gl.BOOL:              "bool",
+	gl.FLOAT_MAT3:        "mat3",
+	gl.FLOAT_MAT4:        "mat4",
+	gl.SAMPLER_2D:        "sampler2D",
+	gl.SAMPLER_2D_ARRAY:  "sampler2DArray",
+	gl.SAMPLER_2D_SHADOW: "sampler2DShadow",
+	gl.SAMPLER_3D:        "sampler3D",
+	gl.SAMPLER_CUBE:      "samplerCube",
+}
+
+func uniformTypeName(t uint32) string {
+	if name, ok := uniformTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("type 0x%x", t)
+}
+
+//location checks the type and the array length of the uniform and returns its location,
+//unknown uniform returns -1 which is ignored by gl, or an error in debug builds
+func (p Program) location(name string, count int, types ...uint32) (int32, error) {
+	u, ok := p.info().uniforms[name]
+	if !ok {
+		if debug {
+			return -1, fmt.Errorf("program %d has no active uniform %s", p, name)
+		}
+		return -1, nil
+	}
+	typeOK := false
+	for _, t := range types {
+		if u.Type == t {
+			typeOK = true
+			break
+		}
+	}
+	if !typeOK {
+		return -1, fmt.Errorf("uniform %s is %s, not %s", name, uniformTypeName(u.Type), uniformTypeName(types[0]))
+	}
+	if count > int(u.Size) {
+		return -1, fmt.Errorf("uniform %s has %d elements, %d set", name, u.Size, count)
+	}
+	return int32(u.Location), nil
+}
+
+//SetFloat sets the float uniform
+func (p Program) SetFloat(name string, v float32) error {
+	l, err := p.location(name, 1, gl.FLOAT)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform1f(uint32(p), l, v)
+	return nil
+}
+
+//SetVec2 sets the vec2 uniform
+func (p Program) SetVec2(name string, v mgl32.Vec2) error {
+	l, err := p.location(name, 1, gl.FLOAT_VEC2)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform2f(uint32(p), l, v[0], v[1])
+	return nil
+}
+
+//SetVec3 sets the vec3 uniform
+func (p Program) SetVec3(name string, v mgl32.Vec3) error {
+	l, err := p.location(name, 1, gl.FLOAT_VEC3)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform3f(uint32(p), l, v[0], v[1], v[2])
+	return nil
+}
+
+//SetVec4 sets the vec4 uniform
+func (p Program) SetVec4(name string, v mgl32.Vec4) error {
+	l, err := p.location(name, 1, gl.FLOAT_VEC4)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform4f(uint32(p), l, v[0], v[1], v[2], v[3])
+	return nil
+}
+
+//SetInt sets the int or bool uniform
+func (p Program) SetInt(name string, v int32) error {
+	l, err := p.location(name, 1, gl.INT, gl.BOOL)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform1i(uint32(p), l, v)
+	return nil
+}
+
+//SetBool sets the bool uniform
+func (p Program) SetBool(name string, v bool) error {
+	var i int32
+	if v {
+		i = 1
+	}
+	return p.SetInt(name, i)
+}
+
+//SetIVec2 sets the ivec2 uniform
+func (p Program) SetIVec2(name string, v [2]int32) error {
+	l, err := p.location(name, 1, gl.INT_VEC2)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform2i(uint32(p), l, v[0], v[1])
+	return nil
+}
+
+//SetIVec3 sets the ivec3 uniform
+func (p Program) SetIVec3(name string, v [3]int32) error {
+	l, err := p.location(name, 1, gl.INT_VEC3)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform3i(uint32(p), l, v[0], v[1], v[2])
+	return nil
+}
+
+//SetIVec4 sets the ivec4 uniform
+func (p Program) SetIVec4(name string, v [4]int32) error {
+	l, err := p.location(name, 1, gl.INT_VEC4)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform4i(uint32(p), l, v[0], v[1], v[2], v[3])
+	return nil
+}
+
+//SetMat3 sets the mat3 uniform
+func (p Program) SetMat3(name string, m mgl32.Mat3) error {
+	l, err := p.location(name, 1, gl.FLOAT_MAT3)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniformMatrix3fv(uint32(p), l, 1, false, &m[0])
+	return nil
+}
+
+//SetMat4 sets the mat4 uniform
+func (p Program) SetMat4(name string, m mgl32.Mat4) error {
+	l, err := p.location(name, 1, gl.FLOAT_MAT4)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniformMatrix4fv(uint32(p), l, 1, false, &m[0])
+	return nil
+}
+
+//SetSampler sets the texture unit of the sampler uniform
+func (p Program) SetSampler(name string, unit int32) error {
+	l, err := p.location(name, 1, samplerTypes...)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform1i(uint32(p), l, unit)
+	return nil
+}
+
+//SetFloatArray sets the elements of the float array uniform from the first one
+func (p Program) SetFloatArray(name string, v []float32) error {
+	if len(v) == 0 {
+		return nil
+	}
+	l, err := p.location(name, len(v), gl.FLOAT)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform1fv(uint32(p), l, int32(len(v)), &v[0])
+	return nil
+}
+
+//SetIntArray sets the elements of the int array uniform from the first one
+func (p Program) SetIntArray(name string, v []int32) error {
+	if len(v) == 0 {
+		return nil
+	}
+	l, err := p.location(name, len(v), gl.INT)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform1iv(uint32(p), l, int32(len(v)), &v[0])
+	return nil
+}
+
+//SetVec2Array sets the elements of the vec2 array uniform from the first one
+func (p Program) SetVec2Array(name string, v []mgl32.Vec2) error {
+	if len(v) == 0 {
+		return nil
+	}
+	l, err := p.location(name, len(v), gl.FLOAT_VEC2)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform2fv(uint32(p), l, int32(len(v)), &v[0][0])
+	return nil
+}
+
+//SetVec3Array sets the elements of the vec3 array uniform from the first one
+func (p Program) SetVec3Array(name string, v []mgl32.Vec3) error {
+	if len(v) == 0 {
+		return nil
+	}
+	l, err := p.location(name, len(v), gl.FLOAT_VEC3)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform3fv(uint32(p), l, int32(len(v)), &v[0][0])
+	return nil
+}
+
+//SetVec4Array sets the elements of the vec4 array uniform from the first one
+func (p Program) SetVec4Array(name string, v []mgl32.Vec4) error {
+	if len(v) == 0 {
+		return nil
+	}
+	l, err := p.location(name, len(v), gl.FLOAT_VEC4)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniform4fv(uint32(p), l, int32(len(v)), &v[0][0])
+	return nil
+}
+
+//SetMat4Array sets the elements of the mat4 array uniform from the first one
+func (p Program) SetMat4Array(name string, m []mgl32.Mat4) error {
+	if len(m) == 0 {
+		return nil
+	}
+	l, err := p.location(name, len(m), gl.FLOAT_MAT4)
+	if err != nil {
+		return err
+	}
+	gl.ProgramUniformMatrix4fv(uint32(p), l, int32(len(m)), false, &m[0][0])
+	return nil
+}
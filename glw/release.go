@@ -0,0 +1,8 @@
+//go:build !debug
+// +build !debug
+
+package glw
+
+//debug reports the unknown uniform names as errors,
+//the release builds ignore them as gl does, build with -tags debug to enable it
+const debug = false
@@ -2,7 +2,57 @@ package glw
 
 import "github.com/go-gl/mathgl/mgl32"
 
+//Layer orders the renderers of the app, lower layers are rendered first
+type Layer int
+
+//Layers of the app
+const (
+	WorldLayer Layer = iota
+	OverlayLayer
+	UILayer
+	layerCount
+)
+
 //Renderer interface is represents an renderer that will be used in the main loop of the application
+//alpha is the fraction of the update step elapsed since the last update, used to interpolate the state
 type Renderer interface {
-	Render(projView mgl32.Mat4, elapsed float64)
+	Render(projView mgl32.Mat4, alpha float64)
+}
+
+//Updater is updated by the main loop of the application in fixed time steps of dt seconds
+type Updater interface {
+	Update(dt float64)
+}
+
+//UpdaterFunc is an ordinary function used as Updater
+type UpdaterFunc func(dt float64)
+
+//Update calls f(dt)
+func (f UpdaterFunc) Update(dt float64) {
+	f(dt)
+}
+
+//Initializer is a renderer that creates its resources when it's added to the app
+type Initializer interface {
+	Init(app *App) error
+}
+
+//Resizer is a renderer that is notified when the framebuffer size changes
+type Resizer interface {
+	Resize(width, height int)
+}
+
+//Destroyer is a renderer that deletes its resources when it's removed from the app
+type Destroyer interface {
+	Destroy()
+}
+
+//LifecycleRenderer is a renderer driven by the app trough its whole lifecycle,
+//the renderers may implement only some of the interfaces, the app checks for each of them
+type LifecycleRenderer interface {
+	Initializer
+	Updater
+	Renderer
+	Resizer
+	Destroyer
 }
@@ -0,0 +1,189 @@
+package glw
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//Actions handled by the app itself
+const (
+	ScreenshotAction       = "screenshot"
+	ScreenshotHiResAction  = "screenshot_hires"
+	CaptureAction          = "capture"
+	screenshotTimeFormat   = "2006-01-02_15.04.05.000"
+	defaultScreenshotDir   = "screenshots"
+	defaultScreenshotScale = 4
+)
+
+//Screenshot saves the default framebuffer into timestamped png in the dir and returns its path,
+//it must be called after the frame is rendered and before the buffers are swapped
+func (app *App) Screenshot(dir string) (string, error) {
+	return saveScreenshot(dir, app.ReadPixels())
+}
+
+//ScreenshotSize renders a frame into an offscreen framebuffer of arbitrary size
+//and saves it into timestamped png in the dir
+func (app *App) ScreenshotSize(dir string, width, height int) (string, error) {
+	fb, err := NewFramebuffer(FramebufferConfig{
+		Width:  width,
+		Height: height,
+		Colors: []Attachment{{InternalFormat: ColorAttachmentRGBA8.InternalFormat, Renderbuffer: true}},
+		Depth:  &DepthRenderbuffer,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer fb.Delete()
+	//the post-processing chain of the window isn't reallocated to the size,
+	//the frame is supersampled so the screenshot chain isn't multisampled
+	post := app.post
+	if post != nil {
+		shot, err := post.sized(width, height, 0)
+		if err != nil {
+			return "", err
+		}
+		defer shot.deleteFramebuffers()
+		app.post = shot
+	}
+	//render into the framebuffer as if it was the default one
+	offscreen := app.offscreen
+	app.offscreen = fb
+	app.framebufferSize(app.window, 0, 0)
+	app.render(app.alpha)
+	img := fb.ReadPixels(0)
+	app.offscreen, app.post = offscreen, post
+	app.framebufferSize(app.window, 0, 0)
+	app.BindDefaultFramebuffer()
+	return saveScreenshot(dir, img)
+}
+
+func saveScreenshot(dir string, img image.Image) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, time.Now().Format(screenshotTimeFormat)+".png")
+	return path, SavePNG(path, img)
+}
+
+//capture writes the rendered frames as numbered pngs
+type capture struct {
+	dir    string
+	every  int
+	frame  int
+	frames chan *image.RGBA
+	wg     sync.WaitGroup
+	err    error
+}
+
+//StartCapture starts writing every n-th frame into numbered pngs in a new timestamped directory in dir,
+//the images are encoded in background
+func (app *App) StartCapture(dir string, every int) error {
+	if app.capture != nil {
+		return fmt.Errorf("frames are already captured into %s", app.capture.dir)
+	}
+	if every < 1 {
+		every = 1
+	}
+	c := &capture{
+		dir:    filepath.Join(dir, time.Now().Format(screenshotTimeFormat)),
+		every:  every,
+		frames: make(chan *image.RGBA, 8),
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	c.wg.Add(1)
+	go c.encode()
+	app.capture = c
+	return nil
+}
+
+//StopCapture waits until the captured frames are written and returns the first error
+func (app *App) StopCapture() error {
+	c := app.capture
+	if c == nil {
+		return nil
+	}
+	app.capture = nil
+	close(c.frames)
+	c.wg.Wait()
+	return c.err
+}
+
+//Capturing reports whether the frames are captured
+func (app *App) Capturing() bool {
+	return app.capture != nil
+}
+
+//captureFrame reads the rendered frame if it's the n-th one
+func (c *capture) captureFrame(app *App) {
+	if c.frame%c.every == 0 {
+		c.frames <- app.ReadPixels()
+	}
+	c.frame++
+}
+
+func (c *capture) encode() {
+	defer c.wg.Done()
+	n := 0
+	for img := range c.frames {
+		if c.err != nil {
+			continue
+		}
+		c.err = SavePNG(filepath.Join(c.dir, fmt.Sprintf("%06d.png", n)), img)
+		n++
+	}
+}
+
+//screenshotActions consumes the screenshot actions at the start of the frame,
+//so the edges aren't cleared by the update steps before and aren't seen again by the next frames
+func (app *App) screenshotActions() {
+	if app.Input.ConsumePressed(ScreenshotAction) {
+		app.screenshotScale = 1
+	}
+	if app.Input.ConsumePressed(ScreenshotHiResAction) {
+		app.screenshotScale = defaultScreenshotScale
+	}
+	if app.Input.ConsumePressed(CaptureAction) {
+		var err error
+		if app.Capturing() {
+			dir := app.capture.dir
+			if err = app.StopCapture(); err == nil {
+				log.Println("frames captured into", dir)
+			}
+		} else {
+			err = app.StartCapture(app.ScreenshotDir, 1)
+		}
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+//afterRender takes the requested screenshot and captures the frame
+func (app *App) afterRender() {
+	if app.screenshotScale > 0 {
+		var path string
+		var err error
+		if app.screenshotScale == 1 {
+			path, err = app.Screenshot(app.ScreenshotDir)
+		} else {
+			width, height := app.FramebufferSize()
+			path, err = app.ScreenshotSize(app.ScreenshotDir, width*app.screenshotScale, height*app.screenshotScale)
+		}
+		if err != nil {
+			log.Println(err)
+		} else {
+			log.Println("screenshot saved to", path)
+		}
+		app.screenshotScale = 0
+	}
+	if app.capture != nil {
+		app.capture.captureFrame(app)
+	}
+}
@@ -0,0 +1,162 @@
+package glw
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//defaultPollInterval is how often the shader files are checked for changes
+const defaultPollInterval = 500 * time.Millisecond
+
+//ShaderFile is the path relative to the preprocessor root and the type of the shader source file
+type ShaderFile struct {
+	Type uint32
+	Path string
+}
+
+//ShaderProgram is the program compiled from the shader files, which is recompiled when the files
+//or the files included by them change
+type ShaderProgram struct {
+	files    []ShaderFile
+	pp       *Preprocessor
+	modTimes map[string]time.Time //modification times of the read files including the included ones
+	program  Program
+
+	//OnReload is called with the new program after it replaces the old one,
+	//the uniform and attribute locations should be resolved again
+	OnReload func(p Program) error
+	//PollInterval is the minimal time between the checks of the files
+	PollInterval time.Duration
+	lastPoll     time.Time
+}
+
+//NewShaderProgram compiles and links the program from the shader files preprocessed by pp,
+//nil pp reads the files from the DefaultShaderRoot without any defines.
+//The changed defines of pp are used after Reload
+func NewShaderProgram(pp *Preprocessor, files ...ShaderFile) (*ShaderProgram, error) {
+	if pp == nil {
+		pp = NewPreprocessor(DefaultShaderRoot)
+	}
+	sp := &ShaderProgram{
+		files:        files,
+		pp:           pp,
+		PollInterval: defaultPollInterval,
+	}
+	program, err := sp.compile()
+	if err != nil {
+		return nil, err
+	}
+	sp.program = program
+	return sp, nil
+}
+
+//Program returns the current program
+func (sp *ShaderProgram) Program() Program {
+	return sp.program
+}
+
+//compile preprocesses the files and links a new program, it records the modification times of the files read
+//by all the stages, even if some of them fail
+func (sp *ShaderProgram) compile() (Program, error) {
+	sp.modTimes = make(map[string]time.Time)
+	sources := make([]ShaderSource, len(sp.files))
+	var firstErr error
+	for i, f := range sp.files {
+		source, files, err := sp.pp.Process(f.Path)
+		//the files are watched even if they are broken or missing, so the fix is picked up
+		for _, path := range files {
+			var modTime time.Time
+			if info, err := os.Stat(filepath.Join(sp.pp.Root, path)); err == nil {
+				modTime = info.ModTime()
+			}
+			sp.modTimes[path] = modTime
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		sources[i] = ShaderSource{Type: f.Type, Source: strings.NewReader(source), Name: f.Path, Files: files}
+	}
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	return NewProgram(sources...)
+}
+
+//Reload recompiles the program, the old program is kept if the compilation or linking fails,
+//the error of OnReload or of the validation in the debug builds is returned after the program is replaced
+func (sp *ShaderProgram) Reload() error {
+	program, err := sp.compile()
+	if err != nil {
+		return err
+	}
+	sp.program.Delete()
+	sp.program = program
+	if sp.OnReload != nil {
+		if err := sp.OnReload(program); err != nil {
+			return err
+		}
+	}
+	return sp.validate()
+}
+
+//SetOnReload sets the OnReload callback and calls it with the current program
+func (sp *ShaderProgram) SetOnReload(onReload func(p Program) error) error {
+	sp.OnReload = onReload
+	if err := onReload(sp.program); err != nil {
+		return err
+	}
+	return sp.validate()
+}
+
+//validate validates the program in the debug builds, the samplers must be already set by OnReload
+func (sp *ShaderProgram) validate() error {
+	if !debug {
+		return nil
+	}
+	return sp.program.Validate()
+}
+
+//changed reports whether any of the files was modified since the last compilation
+func (sp *ShaderProgram) changed() bool {
+	for path, modTime := range sp.modTimes {
+		info, err := os.Stat(filepath.Join(sp.pp.Root, path))
+		if err != nil {
+			//the file may be replaced by the editor right now or it's still missing
+			continue
+		}
+		if !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+//Poll reloads the program if the files changed, the errors are logged
+//and the old program is used until the files are fixed, returns true if the program was reloaded
+func (sp *ShaderProgram) Poll() bool {
+	now := time.Now()
+	if now.Sub(sp.lastPoll) < sp.PollInterval {
+		return false
+	}
+	sp.lastPoll = now
+	if !sp.changed() {
+		return false
+	}
+	if err := sp.Reload(); err != nil {
+		log.Println("shader reload failed:", err)
+		return false
+	}
+	log.Println("shader reloaded:", sp.files[0].Path)
+	return true
+}
+
+//Delete deletes the program
+func (sp *ShaderProgram) Delete() {
+	sp.program.Delete()
+}
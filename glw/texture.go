@@ -15,57 +15,182 @@ import (
 //Texture the gl texture
 type Texture uint32
 
+//TextureOptions configures sampling and storage of a texture
+//zero values are replaced by the defaults (linear filtering, repeat wrapping, unit 0)
+type TextureOptions struct {
+	MinFilter  int32   //gl.LINEAR, gl.NEAREST, gl.NEAREST_MIPMAP_LINEAR, ...
+	MagFilter  int32   //gl.LINEAR or gl.NEAREST
+	WrapS      int32   //gl.REPEAT, gl.CLAMP_TO_EDGE, ...
+	WrapT      int32   //gl.REPEAT, gl.CLAMP_TO_EDGE, ...
+	Mipmaps    bool    //generates the mipmap chain after upload
+	Anisotropy float32 //max anisotropic samples, values <= 1 disable it
+	SRGB       bool    //stores the texels in the sRGB color space
+	Unit       uint32  //texture unit index, 0 means gl.TEXTURE0
+}
+
+func (opts TextureOptions) withDefaults() TextureOptions {
+	if opts.MinFilter == 0 {
+		opts.MinFilter = gl.LINEAR
+		if opts.Mipmaps {
+			opts.MinFilter = gl.LINEAR_MIPMAP_LINEAR
+		}
+	}
+	if opts.MagFilter == 0 {
+		opts.MagFilter = gl.LINEAR
+	}
+	if opts.WrapS == 0 {
+		opts.WrapS = gl.REPEAT
+	}
+	if opts.WrapT == 0 {
+		opts.WrapT = gl.REPEAT
+	}
+	return opts
+}
+
 //NewTexture creates new texture from img file
-func NewTexture(r io.Reader) (Texture, error) {
+func NewTexture(r io.Reader, opts TextureOptions) (Texture, error) {
 	img, _, err := image.Decode(r)
 	if err != nil {
 		return 0, fmt.Errorf("cannot decode image: %s", err)
 	}
-
-	var texture uint32
-	gl.GenTextures(1, &texture)
-	gl.ActiveTexture(gl.TEXTURE0)
-	gl.BindTexture(gl.TEXTURE_2D, texture)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	//gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-	//gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
-
-	switch trueim := img.(type) {
-	case *image.RGBA:
-		gl.TexImage2D(
-			gl.TEXTURE_2D, 0, gl.RGBA,
-			int32(trueim.Bounds().Dx()), int32(trueim.Bounds().Dy()),
-			0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(trueim.Pix),
-		)
-	default:
-		copy := image.NewRGBA(trueim.Bounds())
-		draw.Draw(copy, trueim.Bounds(), trueim, image.Pt(0, 0), draw.Src)
-		gl.TexImage2D(
-			gl.TEXTURE_2D, 0, gl.RGBA,
-			int32(copy.Bounds().Dx()), int32(copy.Bounds().Dy()),
-			0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(copy.Pix),
-		)
-	}
-
-	return Texture(texture), nil
+	return NewTextureFromImage(img, opts), nil
 }
 
 //NewTextureFromFile loads texture from filepath
-func NewTextureFromFile(filepath string) (Texture, error) {
+func NewTextureFromFile(filepath string, opts TextureOptions) (Texture, error) {
 	f, err := os.Open(filepath)
 	if err != nil {
 		return 0, err
 	}
 	defer f.Close()
-	texture, err := NewTexture(f)
+	texture, err := NewTexture(f, opts)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%s: %s", filepath, err)
 	}
 	return texture, nil
 }
 
+//NewTextureFromImage uploads the decoded image to a new texture
+func NewTextureFromImage(img image.Image, opts TextureOptions) Texture {
+	opts = opts.withDefaults()
+
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	t := Texture(texture)
+	t.BindToUnit(opts.Unit)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, opts.MinFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, opts.MagFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, opts.WrapS)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, opts.WrapT)
+	if opts.Anisotropy > 1 {
+		var max float32
+		gl.GetFloatv(gl.MAX_TEXTURE_MAX_ANISOTROPY, &max)
+		if opts.Anisotropy > max {
+			opts.Anisotropy = max
+		}
+		if opts.Anisotropy > 1 {
+			gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAX_ANISOTROPY, opts.Anisotropy)
+		}
+	}
+
+	var internalFormat int32 = gl.RGBA8
+	if opts.SRGB {
+		internalFormat = gl.SRGB8_ALPHA8
+	}
+	rgba := toRGBA(img)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage2D(
+		gl.TEXTURE_2D, 0, internalFormat,
+		int32(rgba.Bounds().Dx()), int32(rgba.Bounds().Dy()),
+		0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix),
+	)
+	if opts.Mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	return t
+}
+
+//toRGBA returns the image as tightly packed *image.RGBA starting at 0,0
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) && rgba.Stride == 4*rgba.Rect.Dx() {
+		return rgba
+	}
+	copy := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(copy, copy.Bounds(), img, img.Bounds().Min, draw.Src)
+	return copy
+}
+
 //BindTexture ...
 func (t Texture) BindTexture() {
 	gl.BindTexture(gl.TEXTURE_2D, uint32(t))
 }
+
+//BindToUnit activates the texture unit and binds the texture to it
+func (t Texture) BindToUnit(unit uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(gl.TEXTURE_2D, uint32(t))
+}
+
+//BindArrayToUnit activates the texture unit and binds the 2D array texture to it
+func (t Texture) BindArrayToUnit(unit uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, uint32(t))
+}
+
+//Size returns the width and height of the base level
+func (t Texture) Size() (width, height int) {
+	var w, h int32
+	t.BindTexture()
+	gl.GetTexLevelParameteriv(gl.TEXTURE_2D, 0, gl.TEXTURE_WIDTH, &w)
+	gl.GetTexLevelParameteriv(gl.TEXTURE_2D, 0, gl.TEXTURE_HEIGHT, &h)
+	return int(w), int(h)
+}
+
+//SubImage replaces the region of the texture starting at x, y with img
+//and regenerates the mipmaps if the texture uses them
+func (t Texture) SubImage(x, y int, img image.Image) error {
+	w, h := t.Size()
+	rgba := toRGBA(img)
+	dx, dy := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if x < 0 || y < 0 || x+dx > w || y+dy > h {
+		return fmt.Errorf("region %dx%d at %d,%d is out of the texture bounds %dx%d", dx, dy, x, y, w, h)
+	}
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, int32(x), int32(y), int32(dx), int32(dy), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	t.regenerateMipmaps()
+	return nil
+}
+
+//SetImage replaces the whole texture with img keeping its format and sampling options
+func (t Texture) SetImage(img image.Image) {
+	var internalFormat int32
+	t.BindTexture()
+	gl.GetTexLevelParameteriv(gl.TEXTURE_2D, 0, gl.TEXTURE_INTERNAL_FORMAT, &internalFormat)
+	rgba := toRGBA(img)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage2D(
+		gl.TEXTURE_2D, 0, internalFormat,
+		int32(rgba.Bounds().Dx()), int32(rgba.Bounds().Dy()),
+		0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix),
+	)
+	t.regenerateMipmaps()
+}
+
+func (t Texture) regenerateMipmaps() {
+	var minFilter int32
+	gl.GetTexParameteriv(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, &minFilter)
+	switch minFilter {
+	case gl.NEAREST, gl.LINEAR:
+	default:
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+}
+
+//Delete deletes the texture
+func (t Texture) Delete() {
+	if t == 0 {
+		return
+	}
+	texture := uint32(t)
+	gl.DeleteTextures(1, &texture)
+}
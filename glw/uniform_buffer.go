@@ -0,0 +1,267 @@
+package glw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+//UniformBuffer is the uniform buffer object with the std140 layout of a Go struct,
+//the fields of the struct map to the members of the uniform block in the same order:
+//float32, int32, uint32 and bool to the scalars, mgl32.Vec2-4 to the vectors,
+//mgl32.Mat3-4 to the matrices, Go arrays to the arrays and structs to the structs
+type UniformBuffer struct {
+	id      uint32
+	binding uint32
+	t       reflect.Type
+	fields  map[string]uniformField
+	data    []byte //content of the buffer on the gpu
+	scratch []byte
+}
+
+type uniformField struct {
+	offset int
+	size   int
+	t      reflect.Type
+}
+
+var (
+	vec2Type = reflect.TypeOf(mgl32.Vec2{})
+	vec3Type = reflect.TypeOf(mgl32.Vec3{})
+	vec4Type = reflect.TypeOf(mgl32.Vec4{})
+	mat3Type = reflect.TypeOf(mgl32.Mat3{})
+	mat4Type = reflect.TypeOf(mgl32.Mat4{})
+)
+
+//NewUniformBuffer creates the uniform buffer with the layout of the struct v, uploads v
+//and binds the buffer to the binding point
+func NewUniformBuffer(binding uint32, v interface{}) (*UniformBuffer, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	t := value.Type()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("uniform buffer needs struct, got %s", t)
+	}
+	size, _, err := std140Layout(t)
+	if err != nil {
+		return nil, err
+	}
+	ub := &UniformBuffer{
+		binding: binding,
+		t:       t,
+		fields:  std140Fields(t),
+		data:    make([]byte, size),
+		scratch: make([]byte, size),
+	}
+	std140Encode(ub.data, 0, value)
+	gl.GenBuffers(1, &ub.id)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ub.id)
+	gl.BufferData(gl.UNIFORM_BUFFER, len(ub.data), gl.Ptr(ub.data), gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, binding, ub.id)
+	return ub, nil
+}
+
+//Binding returns the binding point of the buffer
+func (ub *UniformBuffer) Binding() uint32 {
+	return ub.binding
+}
+
+//Size returns the size of the buffer in bytes
+func (ub *UniformBuffer) Size() int {
+	return len(ub.data)
+}
+
+//Set encodes v and uploads only the range of the bytes which changed
+func (ub *UniformBuffer) Set(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Type() != ub.t {
+		return fmt.Errorf("uniform buffer of %s can't be set to %s", ub.t, value.Type())
+	}
+	std140Encode(ub.scratch, 0, value)
+	first, last := -1, -1
+	for i := range ub.scratch {
+		if ub.scratch[i] != ub.data[i] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	ub.upload(first, ub.scratch[first:last+1])
+	return nil
+}
+
+//SetField encodes and uploads only the field of the struct
+func (ub *UniformBuffer) SetField(name string, v interface{}) error {
+	f, ok := ub.fields[name]
+	if !ok {
+		return fmt.Errorf("uniform buffer of %s has no field %s", ub.t, name)
+	}
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Type() != f.t {
+		return fmt.Errorf("field %s of %s is %s, not %s", name, ub.t, f.t, value.Type())
+	}
+	buf := ub.scratch[f.offset : f.offset+f.size]
+	std140Encode(ub.scratch, f.offset, value)
+	ub.upload(f.offset, buf)
+	return nil
+}
+
+func (ub *UniformBuffer) upload(offset int, buf []byte) {
+	copy(ub.data[offset:], buf)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ub.id)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, offset, len(buf), gl.Ptr(buf))
+}
+
+//Bind binds the buffer to its binding point again, after the binding point was used by other buffer
+func (ub *UniformBuffer) Bind() {
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, ub.binding, ub.id)
+}
+
+//Delete deletes the buffer
+func (ub *UniformBuffer) Delete() {
+	gl.DeleteBuffers(1, &ub.id)
+	ub.id = 0
+}
+
+//BindUniformBlock assigns the uniform block of the program to the binding point of the buffer
+//and checks that the sizes of the block and the buffer match,
+//missing block is an error in debug builds
+func (p Program) BindUniformBlock(name string, ub *UniformBuffer) error {
+	index := gl.GetUniformBlockIndex(uint32(p), gl.Str(name+"\x00"))
+	if index == gl.INVALID_INDEX {
+		if debug {
+			return fmt.Errorf("program %d has no active uniform block %s", p, name)
+		}
+		return nil
+	}
+	var size int32
+	gl.GetActiveUniformBlockiv(uint32(p), index, gl.UNIFORM_BLOCK_DATA_SIZE, &size)
+	//drivers may or may not pad the size of the block to vec4
+	if alignUp(int(size), 16) != alignUp(ub.Size(), 16) {
+		return fmt.Errorf("uniform block %s has %d bytes, the buffer of %s has %d, check the std140 layout", name, size, ub.t, ub.Size())
+	}
+	gl.UniformBlockBinding(uint32(p), index, ub.binding)
+	return nil
+}
+
+func alignUp(offset, align int) int {
+	return (offset + align - 1) / align * align
+}
+
+//std140Layout returns the size and the base alignment of the type in the std140 layout
+func std140Layout(t reflect.Type) (size, align int, err error) {
+	switch t {
+	case vec2Type:
+		return 8, 8, nil
+	case vec3Type:
+		return 12, 16, nil
+	case vec4Type:
+		return 16, 16, nil
+	case mat3Type:
+		//columns are vec3 padded to vec4
+		return 48, 16, nil
+	case mat4Type:
+		return 64, 16, nil
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Int32, reflect.Uint32, reflect.Bool:
+		return 4, 4, nil
+	case reflect.Array:
+		esize, _, err := std140Layout(t.Elem())
+		if err != nil {
+			return 0, 0, err
+		}
+		//the elements of arrays are aligned to vec4
+		return alignUp(esize, 16) * t.Len(), 16, nil
+	case reflect.Struct:
+		offset, maxAlign := 0, 16
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fsize, falign, err := std140Layout(f.Type)
+			if err != nil {
+				return 0, 0, fmt.Errorf("%s.%s: %s", t.Name(), f.Name, err)
+			}
+			if falign > maxAlign {
+				maxAlign = falign
+			}
+			offset = alignUp(offset, falign) + fsize
+		}
+		return alignUp(offset, maxAlign), maxAlign, nil
+	}
+	return 0, 0, fmt.Errorf("type %s can't be stored in uniform buffer", t)
+}
+
+//std140Fields returns the offsets and sizes of the fields of the struct with valid std140 layout
+func std140Fields(t reflect.Type) map[string]uniformField {
+	fields := make(map[string]uniformField)
+	offset := 0
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fsize, falign, _ := std140Layout(f.Type)
+		offset = alignUp(offset, falign)
+		fields[f.Name] = uniformField{offset: offset, size: fsize, t: f.Type}
+		offset += fsize
+	}
+	return fields
+}
+
+//std140Encode writes v at the offset of buf in the std140 layout
+func std140Encode(buf []byte, offset int, v reflect.Value) {
+	putFloat := func(o int, f float32) {
+		binary.LittleEndian.PutUint32(buf[o:], math.Float32bits(f))
+	}
+	switch v.Type() {
+	case vec2Type, vec3Type, vec4Type:
+		for i := 0; i < v.Len(); i++ {
+			putFloat(offset+4*i, float32(v.Index(i).Float()))
+		}
+		return
+	case mat3Type:
+		for c := 0; c < 3; c++ {
+			for r := 0; r < 3; r++ {
+				putFloat(offset+16*c+4*r, float32(v.Index(3*c+r).Float()))
+			}
+		}
+		return
+	case mat4Type:
+		for i := 0; i < 16; i++ {
+			putFloat(offset+4*i, float32(v.Index(i).Float()))
+		}
+		return
+	}
+	switch v.Kind() {
+	case reflect.Float32:
+		putFloat(offset, float32(v.Float()))
+	case reflect.Int32:
+		binary.LittleEndian.PutUint32(buf[offset:], uint32(int32(v.Int())))
+	case reflect.Uint32:
+		binary.LittleEndian.PutUint32(buf[offset:], uint32(v.Uint()))
+	case reflect.Bool:
+		var b uint32
+		if v.Bool() {
+			b = 1
+		}
+		binary.LittleEndian.PutUint32(buf[offset:], b)
+	case reflect.Array:
+		esize, _, _ := std140Layout(v.Type().Elem())
+		stride := alignUp(esize, 16)
+		for i := 0; i < v.Len(); i++ {
+			std140Encode(buf, offset+i*stride, v.Index(i))
+		}
+	case reflect.Struct:
+		o := offset
+		for i := 0; i < v.NumField(); i++ {
+			fsize, falign, _ := std140Layout(v.Field(i).Type())
+			o = alignUp(o, falign)
+			std140Encode(buf, o, v.Field(i))
+			o += fsize
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package glw
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+//perFrameLayout is the layout of the PerFrame block in assets/shaders/per_frame.glsl
+type perFrameLayout struct {
+	ProjView     mgl32.Mat4
+	CameraPos    mgl32.Vec3
+	Time         float32
+	SunDir       mgl32.Vec3
+	Ambient      float32
+	HorizonColor mgl32.Vec3
+	FogStart     float32
+	ZenithColor  mgl32.Vec3
+	FogEnd       float32
+}
+
+//shadowLayout is the layout of the Shadows block in assets/shaders/shadow.glsl
+type shadowLayout struct {
+	Matrices [4]mgl32.Mat4
+	Splits   mgl32.Vec4
+	Bias     mgl32.Vec4
+	ViewDir  mgl32.Vec3
+}
+
+func TestStd140Fields(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		size    int
+		offsets map[string]int
+	}{
+		{
+			name: "per frame",
+			v:    perFrameLayout{},
+			size: 128,
+			offsets: map[string]int{
+				"ProjView": 0, "CameraPos": 64, "Time": 76, "SunDir": 80, "Ambient": 92,
+				"HorizonColor": 96, "FogStart": 108, "ZenithColor": 112, "FogEnd": 124,
+			},
+		},
+		{
+			name:    "shadows",
+			v:       shadowLayout{},
+			size:    304,
+			offsets: map[string]int{"Matrices": 0, "Splits": 256, "Bias": 272, "ViewDir": 288},
+		},
+		{
+			name: "scalars and vectors",
+			v: struct {
+				F  float32
+				V2 mgl32.Vec2
+				V3 mgl32.Vec3
+				B  bool
+				V4 mgl32.Vec4
+			}{},
+			size:    48,
+			offsets: map[string]int{"F": 0, "V2": 8, "V3": 16, "B": 28, "V4": 32},
+		},
+		{
+			name: "float array and mat3",
+			v: struct {
+				A [3]float32
+				I int32
+				M mgl32.Mat3
+			}{},
+			size:    112,
+			offsets: map[string]int{"A": 0, "I": 48, "M": 64},
+		},
+		{
+			name: "nested struct",
+			v: struct {
+				F float32
+				S struct{ F float32 }
+				U uint32
+			}{},
+			size:    48,
+			offsets: map[string]int{"F": 0, "S": 16, "U": 32},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			typ := reflect.TypeOf(test.v)
+			size, _, err := std140Layout(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != test.size {
+				t.Errorf("size %d, want %d", size, test.size)
+			}
+			fields := std140Fields(typ)
+			for name, offset := range test.offsets {
+				if fields[name].offset != offset {
+					t.Errorf("%s at %d, want %d", name, fields[name].offset, offset)
+				}
+			}
+		})
+	}
+}
+
+func TestStd140LayoutError(t *testing.T) {
+	v := struct {
+		V mgl32.Vec3
+		D float64
+	}{}
+	if _, _, err := std140Layout(reflect.TypeOf(v)); err == nil {
+		t.Error("float64 field has std140 layout")
+	}
+}
+
+func encode(t *testing.T, v interface{}) []byte {
+	size, _, err := std140Layout(reflect.TypeOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, size)
+	std140Encode(buf, 0, reflect.ValueOf(v))
+	return buf
+}
+
+func floatAt(buf []byte, offset int) float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(buf[offset:]))
+}
+
+func TestStd140EncodeVec3Float(t *testing.T) {
+	buf := encode(t, perFrameLayout{
+		CameraPos: mgl32.Vec3{1, 2, 3},
+		Time:      4,
+		SunDir:    mgl32.Vec3{5, 6, 7},
+		Ambient:   8,
+	})
+	//the float fills the padding of the vec3
+	for i, want := range []float32{1, 2, 3, 4, 5, 6, 7, 8} {
+		if got := floatAt(buf, 64+4*i); got != want {
+			t.Errorf("float at %d is %f, want %f", 64+4*i, got, want)
+		}
+	}
+}
+
+func TestStd140EncodeMat4Array(t *testing.T) {
+	var v shadowLayout
+	for i := range v.Matrices {
+		v.Matrices[i] = mgl32.Translate3D(float32(i), float32(10*i), float32(100*i))
+	}
+	v.Splits = mgl32.Vec4{1, 2, 3, 4}
+	buf := encode(t, v)
+	for i, m := range v.Matrices {
+		for j, want := range m {
+			if got := floatAt(buf, 64*i+4*j); got != want {
+				t.Errorf("matrix %d element %d is %f, want %f", i, j, got, want)
+			}
+		}
+	}
+	if got := floatAt(buf, 256+12); got != 4 {
+		t.Errorf("splits w after the matrices is %f, want 4", got)
+	}
+}
+
+func TestStd140EncodePadding(t *testing.T) {
+	v := struct {
+		A [3]float32
+		B bool
+		M mgl32.Mat3
+	}{
+		A: [3]float32{1, 2, 3},
+		B: true,
+		M: mgl32.Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9},
+	}
+	buf := encode(t, v)
+	//the array elements are aligned to vec4
+	for i, want := range v.A {
+		if got := floatAt(buf, 16*i); got != want {
+			t.Errorf("array element %d is %f, want %f", i, got, want)
+		}
+	}
+	if got := binary.LittleEndian.Uint32(buf[48:]); got != 1 {
+		t.Errorf("bool is %d, want 1", got)
+	}
+	//the columns of mat3 are padded to vec4
+	for c := 0; c < 3; c++ {
+		for r := 0; r < 3; r++ {
+			if got, want := floatAt(buf, 64+16*c+4*r), v.M[3*c+r]; got != want {
+				t.Errorf("mat3 column %d row %d is %f, want %f", c, r, got, want)
+			}
+		}
+	}
+}
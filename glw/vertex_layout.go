@@ -0,0 +1,119 @@
+package glw
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+//VertexAttribute describes one attribute of the interleaved vertex
+type VertexAttribute struct {
+	Name       string //name of the attribute in the shader
+	Size       int32  //number of the components, 1 to 4
+	Type       uint32 //type of the components, gl.FLOAT, gl.UNSIGNED_BYTE, ...
+	Normalized bool   //integer components are mapped to [0, 1] or [-1, 1] floats
+	Integer    bool   //integer components are passed to int or uint attribute unconverted
+	Offset     int    //offset in the vertex in bytes
+	Divisor    uint32 //the attribute advances once per divisor instances, 0 advances per vertex
+}
+
+//VertexLayout describes the vertices of a buffer
+type VertexLayout struct {
+	Attributes []VertexAttribute
+	Stride     int32 //size of the vertex in bytes
+}
+
+var vertexTypeSizes = map[uint32]int{
+	gl.BYTE:           1,
+	gl.UNSIGNED_BYTE:  1,
+	gl.SHORT:          2,
+	gl.UNSIGNED_SHORT: 2,
+	gl.HALF_FLOAT:     2,
+	gl.INT:            4,
+	gl.UNSIGNED_INT:   4,
+	gl.FLOAT:          4,
+}
+
+//NewVertexLayout creates layout of the attributes packed in the given order,
+//the zero offsets are computed from the end of the previous attribute, the explicitly set offsets are kept,
+//and the stride is the end of the last attribute in the vertex
+func NewVertexLayout(attributes ...VertexAttribute) VertexLayout {
+	layout := VertexLayout{Attributes: make([]VertexAttribute, len(attributes))}
+	offset, stride := 0, 0
+	for i, a := range attributes {
+		if a.Offset == 0 {
+			a.Offset = offset
+		}
+		offset = a.Offset + int(a.Size)*vertexTypeSizes[a.Type]
+		if offset > stride {
+			stride = offset
+		}
+		layout.Attributes[i] = a
+	}
+	layout.Stride = int32(stride)
+	return layout
+}
+
+//attribLocations is the number of the locations used by the matrix attributes, the others use one per element
+var attribLocations = map[uint32]int32{
+	gl.FLOAT_MAT2: 2,
+	gl.FLOAT_MAT3: 3,
+	gl.FLOAT_MAT4: 4,
+}
+
+//disableUnused disables the attribute arrays of the bound vertex array which aren't used by the program,
+//e.g. the locations of the previous program before the shaders were reloaded
+func disableUnused(p Program) {
+	used := make(map[uint32]bool)
+	for _, a := range p.info().attribs {
+		n, ok := attribLocations[a.Type]
+		if !ok {
+			n = 1
+		}
+		for i := int32(0); i < n*a.Size; i++ {
+			used[uint32(a.Location)+uint32(i)] = true
+		}
+	}
+	var max int32
+	gl.GetIntegerv(gl.MAX_VERTEX_ATTRIBS, &max)
+	for location := uint32(0); location < uint32(max); location++ {
+		var enabled int32
+		gl.GetVertexAttribiv(location, gl.VERTEX_ATTRIB_ARRAY_ENABLED, &enabled)
+		if enabled != 0 && !used[location] {
+			gl.DisableVertexAttribArray(location)
+		}
+	}
+}
+
+//SetLayout points the attributes of the vertex array to the buffer using the locations of the program,
+//the attributes not used by the program are skipped, unknown names are errors in debug builds,
+//the locations enabled for other program are disabled.
+//The layout is stored in the vertex array so drawing needs just BindVertexArray,
+//it can be called with multiple buffers, e.g. for per instance attributes
+func (va VertexArray) SetLayout(p Program, layout VertexLayout, buffer Buffer) error {
+	va.BindVertexArray()
+	buffer.BindBuffer()
+	defer gl.BindVertexArray(0)
+	disableUnused(p)
+	for _, a := range layout.Attributes {
+		if _, ok := vertexTypeSizes[a.Type]; !ok {
+			return fmt.Errorf("vertex attribute %s has invalid type 0x%x", a.Name, a.Type)
+		}
+		info, ok := p.Attrib(a.Name)
+		if !ok {
+			if debug {
+				return fmt.Errorf("program %d has no active attribute %s", p, a.Name)
+			}
+			continue
+		}
+		location := info.Location
+		location.EnableVertexAttribArray()
+		if a.Integer {
+			gl.VertexAttribIPointer(uint32(location), a.Size, a.Type, layout.Stride, gl.PtrOffset(a.Offset))
+		} else {
+			gl.VertexAttribPointer(uint32(location), a.Size, a.Type, a.Normalized, layout.Stride, gl.PtrOffset(a.Offset))
+		}
+		gl.VertexAttribDivisor(uint32(location), a.Divisor)
+	}
+	return nil
+}
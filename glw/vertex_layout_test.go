@@ -0,0 +1,50 @@
+package glw
+
+import (
+	"testing"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+func TestNewVertexLayout(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes []VertexAttribute
+		offsets    []int
+		stride     int32
+	}{
+		{
+			name: "packed",
+			attributes: []VertexAttribute{
+				{Name: "vert", Size: 3, Type: gl.FLOAT},
+				{Name: "uv", Size: 2, Type: gl.FLOAT},
+				{Name: "color", Size: 4, Type: gl.UNSIGNED_BYTE, Normalized: true},
+			},
+			offsets: []int{0, 12, 20},
+			stride:  24,
+		},
+		{
+			name: "explicit offsets",
+			attributes: []VertexAttribute{
+				{Name: "vert", Size: 3, Type: gl.FLOAT},
+				{Name: "uv", Size: 2, Type: gl.FLOAT, Offset: 16},
+				{Name: "light", Size: 1, Type: gl.FLOAT},
+			},
+			offsets: []int{0, 16, 24},
+			stride:  28,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layout := NewVertexLayout(test.attributes...)
+			for i, a := range layout.Attributes {
+				if a.Offset != test.offsets[i] {
+					t.Errorf("%s at %d, want %d", a.Name, a.Offset, test.offsets[i])
+				}
+			}
+			if layout.Stride != test.stride {
+				t.Errorf("stride %d, want %d", layout.Stride, test.stride)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package glw
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+//WindowConfig configures the window and the gl context created by NewApp
+type WindowConfig struct {
+	Title      string
+	Width      int //size of the window in screen coordinates
+	Height     int
+	Resizable  bool
+	Samples    int //MSAA samples, 0 disables multisampling
+	VSync      bool
+	Fullscreen bool
+	Borderless bool //fullscreen keeps the desktop video mode of the monitor
+	Monitor    int  //index of the fullscreen monitor in glfw.GetMonitors
+	GLMajor    int  //requested gl version, defaults to 4.5, the lower glFallbackVersions are tried when it isn't available
+	GLMinor    int
+	//Headless creates invisible window and renders into an offscreen framebuffer of the window size,
+	//on machines without GPU run it under Xvfb with Mesa's software rasteriser (LIBGL_ALWAYS_SOFTWARE=1)
+	Headless bool
+}
+
+//DefaultWindowConfig returns resizable window with vsync
+func DefaultWindowConfig() WindowConfig {
+	return WindowConfig{
+		Title:      "craft",
+		Width:      1280,
+		Height:     720,
+		Resizable:  true,
+		VSync:      true,
+		Borderless: true,
+	}.withDefaults()
+}
+
+func (config WindowConfig) withDefaults() WindowConfig {
+	if config.Width == 0 || config.Height == 0 {
+		config.Width, config.Height = 1280, 720
+	}
+	if config.GLMajor == 0 {
+		config.GLMajor, config.GLMinor = 4, 5
+	}
+	return config
+}
+
+//glFallbackVersions are tried in order when the context of the requested version can't be created,
+//4.3 has the multi draw indirect and 4.1 is the last version of macOS
+var glFallbackVersions = [][2]int{{4, 5}, {4, 3}, {4, 1}}
+
+//createWindow creates the window with the context of the configured version or of the highest lower fallback version,
+//the version of the created context is set in the config
+func createWindow(config *WindowConfig) (*glfw.Window, error) {
+	glfw.WindowHint(glfw.ContextVersionMajor, config.GLMajor)
+	glfw.WindowHint(glfw.ContextVersionMinor, config.GLMinor)
+	window, err := glfw.CreateWindow(config.Width, config.Height, config.Title, nil, nil)
+	for _, v := range glFallbackVersions {
+		if err == nil {
+			break
+		}
+		if v[0] > config.GLMajor || v[0] == config.GLMajor && v[1] >= config.GLMinor {
+			continue
+		}
+		log.Printf("cannot create gl %d.%d context, trying %d.%d: %s", config.GLMajor, config.GLMinor, v[0], v[1], err)
+		config.GLMajor, config.GLMinor = v[0], v[1]
+		glfw.WindowHint(glfw.ContextVersionMajor, config.GLMajor)
+		glfw.WindowHint(glfw.ContextVersionMinor, config.GLMinor)
+		window, err = glfw.CreateWindow(config.Width, config.Height, config.Title, nil, nil)
+	}
+	return window, err
+}
+
+func glfwBool(b bool) int {
+	if b {
+		return glfw.True
+	}
+	return glfw.False
+}
+
+//Title returns the title of the window
+func (app *App) Title() string {
+	return app.config.Title
+}
+
+//SetTitle sets the title of the window
+func (app *App) SetTitle(title string) {
+	app.config.Title = title
+	app.window.SetTitle(title)
+}
+
+//Fullscreen reports whether the window is in fullscreen mode
+func (app *App) Fullscreen() bool {
+	return app.window.GetMonitor() != nil
+}
+
+//ToggleFullscreen switches between the fullscreen on the configured monitor and the windowed mode
+func (app *App) ToggleFullscreen() error {
+	return app.SetFullscreen(!app.Fullscreen(), app.config.Monitor)
+}
+
+//SetFullscreen switches the window to fullscreen on the monitor with index from glfw.GetMonitors
+//or back to the windowed mode with the previous position and size
+func (app *App) SetFullscreen(fullscreen bool, monitor int) error {
+	if fullscreen == app.Fullscreen() && (!fullscreen || monitor == app.config.Monitor) {
+		return nil
+	}
+	if !fullscreen {
+		w := app.windowed
+		app.window.SetMonitor(nil, w[0], w[1], w[2], w[3], glfw.DontCare)
+		return nil
+	}
+	monitors := glfw.GetMonitors()
+	if monitor < 0 || monitor >= len(monitors) {
+		return fmt.Errorf("monitor %d doesn't exist, %d monitors connected", monitor, len(monitors))
+	}
+	m := monitors[monitor]
+	if !app.Fullscreen() {
+		x, y := app.window.GetPos()
+		width, height := app.window.GetSize()
+		app.windowed = [4]int{x, y, width, height}
+	}
+	app.config.Monitor = monitor
+	mode := m.GetVideoMode()
+	if app.config.Borderless {
+		app.window.SetMonitor(m, 0, 0, mode.Width, mode.Height, mode.RefreshRate)
+	} else {
+		app.window.SetMonitor(m, 0, 0, app.config.Width, app.config.Height, glfw.DontCare)
+	}
+	//the swap interval may be reset by the mode switch
+	app.SetVSync(app.config.VSync)
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"log"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/microo8/craft/glw"
+)
+
+//highlight renders an outline around the block targeted by the camera
+type highlight struct {
+	w *world
+
+	shader *glw.ShaderProgram
+	p      glw.Program
+	vao    glw.VertexArray
+	buffer glw.Buffer
+}
+
+func newHighlight(w *world) *highlight {
+	return &highlight{w: w}
+}
+
+//Init creates the program and the outline vertices, the world must be initialized first
+func (h *highlight) Init(app *glw.App) error {
+	shader, err := glw.NewShaderProgram(nil,
+		glw.ShaderFile{Type: gl.VERTEX_SHADER, Path: "highlight_vertex_shader.glsl"},
+		glw.ShaderFile{Type: gl.FRAGMENT_SHADER, Path: "highlight_fragment_shader.glsl"},
+	)
+	if err != nil {
+		return err
+	}
+	h.shader = shader
+
+	h.vao = glw.NewVertexArray()
+	h.buffer = glw.NewBuffer(outlineVertices())
+	if err := h.shader.SetOnReload(h.setProgram); err != nil {
+		h.Destroy()
+		return err
+	}
+	return nil
+}
+
+//setProgram sets the program and points the vertex array to the outline buffer
+func (h *highlight) setProgram(p glw.Program) error {
+	h.p = p
+	if err := h.vao.SetLayout(p, outlineLayout, h.buffer); err != nil {
+		return err
+	}
+	if err := p.BindUniformBlock("PerFrame", h.w.frame); err != nil {
+		return err
+	}
+	return p.SetVec4("color", mgl32.Vec4{0, 0, 0, 1})
+}
+
+//Update reloads the shaders when they change
+func (h *highlight) Update(dt float64) {
+	h.shader.Poll()
+}
+
+//Destroy deletes the gl resources of the outline
+func (h *highlight) Destroy() {
+	h.buffer.Delete()
+	h.vao.Delete()
+	h.shader.Delete()
+}
+
+//Render draws the outline of the targeted block
+func (h *highlight) Render(projView mgl32.Mat4, alpha float64) {
+	if !h.w.hasTarget {
+		return
+	}
+	b := h.w.target.Block
+	h.p.UseProgram()
+	h.vao.BindVertexArray()
+	if err := h.p.SetMat4("model", mgl32.Translate3D(float32(b[0]), float32(b[1]), float32(b[2]))); err != nil {
+		log.Println(err)
+	}
+	gl.DrawArrays(gl.LINES, 0, 24)
+}
+
+//outlineLayout is the vertex of the outline lines
+var outlineLayout = glw.NewVertexLayout(glw.VertexAttribute{Name: "vert", Size: 3, Type: gl.FLOAT})
+
+//outlineVertices returns the 12 edges of a unit cube slightly enlarged
+//so the lines don't z-fight with the faces of the block
+func outlineVertices() []float32 {
+	const lo, hi = -0.002, 1.002
+	corner := func(i int) [3]float32 {
+		c := [3]float32{lo, lo, lo}
+		for axis := 0; axis < 3; axis++ {
+			if i&(1<<uint(axis)) != 0 {
+				c[axis] = hi
+			}
+		}
+		return c
+	}
+	var vertices []float32
+	for i := 0; i < 8; i++ {
+		for axis := 0; axis < 3; axis++ {
+			j := i | 1<<uint(axis)
+			if j == i {
+				continue
+			}
+			a, b := corner(i), corner(j)
+			vertices = append(vertices, a[0], a[1], a[2], b[0], b[1], b[2])
+		}
+	}
+	return vertices
+}
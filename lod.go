@@ -0,0 +1,408 @@
+package main
+
+import (
+	"image"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/microo8/craft/glw"
+)
+
+const (
+	//lodLevels is the level of the biggest tiles, the tile of level l spans 2^l chunks
+	lodLevels = 4
+	//lodRadius is the distance of the rendered terrain in chunks
+	lodRadius = 48
+	//lodSplit is the distance in the tile sizes under which the tile is split into the 4 smaller ones
+	lodSplit = 1.5
+	//lodFadeTime is the duration of the dithered fade between the levels in seconds
+	lodFadeTime = 0.5
+	//lodCells is the number of the cells of the tile heightmap in each direction
+	lodCells = chunkSize
+	//chunkFadeUnit is the texture unit of the dither levels of the chunks in the world program
+	chunkFadeUnit = 2
+	//chunkFadeSize is the number of the detail tiles covering the chunk render radius in each direction
+	chunkFadeSize = chunkRenderRadius + 2
+)
+
+//lodTile is the simplified heightmap mesh of the terrain beyond the full detail chunks,
+//its cells are 2^level blocks wide
+type lodTile struct {
+	level    int
+	tp, tq   int
+	vao      glw.VertexArray
+	buffer   glw.Buffer
+	vertices int32
+	min, max mgl32.Vec3
+	fade     float64 //0 to 1 fading in, -1 to 0 fading out
+}
+
+//lodTerrain renders the distant terrain as quadtree of the heightmap tiles generated
+//from the terrain generator without the voxels, the tiles are split near the player
+//and the split tiles of level 1 are replaced by the chunks
+type lodTerrain struct {
+	w      *world
+	shader *glw.ShaderProgram
+	p      glw.Program
+
+	tiles    map[[3]int]*lodTile
+	retiring []*lodTile
+	selected map[[3]int]bool
+	//detail are the tiles of level 1 rendered by the chunks
+	detail map[[2]int]bool
+	//chunkFade fades the chunks of the detail tiles like the tiles, 0 to 1 fading in, -1 to 0 fading out,
+	//so the chunks are dithered with the levels the tiles in their place don't draw
+	chunkFade map[[2]int]float64
+}
+
+func newLODTerrain(w *world) *lodTerrain {
+	l := &lodTerrain{
+		w:         w,
+		tiles:     make(map[[3]int]*lodTile),
+		selected:  make(map[[3]int]bool),
+		detail:    make(map[[2]int]bool),
+		chunkFade: make(map[[2]int]float64),
+	}
+	w.lod = l
+	return l
+}
+
+//Init creates the program of the tiles, the world must be initialized first
+func (l *lodTerrain) Init(app *glw.App) error {
+	shader, err := glw.NewShaderProgram(nil,
+		glw.ShaderFile{Type: gl.VERTEX_SHADER, Path: "vertex_shader.glsl"},
+		glw.ShaderFile{Type: gl.FRAGMENT_SHADER, Path: "lod_fragment_shader.glsl"},
+	)
+	if err != nil {
+		return err
+	}
+	l.shader = shader
+	if err := l.shader.SetOnReload(l.setProgram); err != nil {
+		l.shader.Delete()
+		return err
+	}
+	return nil
+}
+
+//setProgram sets the program and the vertex arrays of the tiles to its attribute locations
+func (l *lodTerrain) setProgram(p glw.Program) error {
+	l.p = p
+	for _, tile := range l.tiles {
+		if err := tile.vao.SetLayout(p, chunkLayout, tile.buffer); err != nil {
+			return err
+		}
+	}
+	if err := p.BindUniformBlock("PerFrame", l.w.frame); err != nil {
+		return err
+	}
+	return p.SetSampler("tex", 0)
+}
+
+//Destroy deletes the tiles and the program
+func (l *lodTerrain) Destroy() {
+	for key, tile := range l.tiles {
+		tile.delete()
+		delete(l.tiles, key)
+	}
+	for _, tile := range l.retiring {
+		tile.delete()
+	}
+	l.retiring = nil
+	l.shader.Delete()
+}
+
+//fullDetail reports whether the chunk is rendered with the voxels, it's still rendered while fading out
+func (l *lodTerrain) fullDetail(p, q int) bool {
+	_, ok := l.chunkFade[[2]int{floorDiv(p, 2), floorDiv(q, 2)}]
+	return ok
+}
+
+//chunkDitherLevels returns the dither levels drawn by the chunks of the detail tile tp, tq
+func (l *lodTerrain) chunkDitherLevels(tp, tq int) (lo, hi int) {
+	fade, ok := l.chunkFade[[2]int{tp, tq}]
+	if !ok {
+		return 0, 0
+	}
+	return ditherLevels(fade)
+}
+
+//ditherLevels returns the range of the 16 dither levels drawn with the fade, which is computed
+//in float32 as in lod_fragment_shader.glsl, so the fading in and out ranges are complementary
+func ditherLevels(fade float64) (lo, hi int) {
+	level := func(f float32) int {
+		return int(math.Max(0, math.Min(16, math.Ceil(float64(16*f)))))
+	}
+	f := float32(fade)
+	if f >= 0 {
+		return 0, level(f)
+	}
+	return level(1 + f), 16
+}
+
+//Update selects the tiles around the player, generates the new ones and fades between the levels
+func (l *lodTerrain) Update(dt float64) {
+	l.shader.Poll()
+	p, q := l.w.player.Chunk()
+	for key := range l.selected {
+		delete(l.selected, key)
+	}
+	for key := range l.detail {
+		delete(l.detail, key)
+	}
+	span := 1 << lodLevels
+	for tp := floorDiv(p-lodRadius, span); tp <= floorDiv(p+lodRadius, span); tp++ {
+		for tq := floorDiv(q-lodRadius, span); tq <= floorDiv(q+lodRadius, span); tq++ {
+			l.selectTile(lodLevels, tp, tq, p, q)
+		}
+	}
+	for key, tile := range l.tiles {
+		if !l.selected[key] {
+			delete(l.tiles, key)
+			if tile.fade > 0 {
+				tile.fade = -tile.fade
+			}
+			l.retiring = append(l.retiring, tile)
+		}
+	}
+	//the first tiles don't fade in
+	initial := len(l.tiles) == 0
+	for key := range l.selected {
+		if _, ok := l.tiles[key]; !ok {
+			tile, err := l.newTile(key[0], key[1], key[2])
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if initial {
+				tile.fade = 1
+			}
+			l.tiles[key] = tile
+		}
+	}
+
+	for key := range l.detail {
+		if fade, ok := l.chunkFade[key]; !ok {
+			l.chunkFade[key] = 0
+			if initial {
+				l.chunkFade[key] = 1
+			}
+		} else if fade < 0 {
+			l.chunkFade[key] = -fade
+		}
+	}
+	for key, fade := range l.chunkFade {
+		if !l.detail[key] && fade > 0 {
+			l.chunkFade[key] = -fade
+		}
+	}
+
+	step := dt / lodFadeTime
+	for key, fade := range l.chunkFade {
+		switch fade += step; {
+		case fade > 1:
+			l.chunkFade[key] = 1
+		case fade >= 0 && !l.detail[key]:
+			delete(l.chunkFade, key)
+		default:
+			l.chunkFade[key] = fade
+		}
+	}
+	for _, tile := range l.tiles {
+		if tile.fade += step; tile.fade > 1 {
+			tile.fade = 1
+		}
+	}
+	retiring := l.retiring[:0]
+	for _, tile := range l.retiring {
+		if tile.fade += step; tile.fade >= 0 {
+			tile.delete()
+			continue
+		}
+		retiring = append(retiring, tile)
+	}
+	l.retiring = retiring
+}
+
+//selectTile selects the tile or its children if the player at chunk p, q is near
+func (l *lodTerrain) selectTile(level, tp, tq, p, q int) {
+	span := 1 << uint(level)
+	p0, q0 := tp*span, tq*span
+	dist := chunkDistance(p, p0, p0+span-1)
+	if dq := chunkDistance(q, q0, q0+span-1); dq > dist {
+		dist = dq
+	}
+	if dist > lodRadius {
+		return
+	}
+	if level == 1 {
+		//the chunks are loaded in the render radius only
+		if abs(p0-p) <= chunkRenderRadius && abs(p0+1-p) <= chunkRenderRadius &&
+			abs(q0-q) <= chunkRenderRadius && abs(q0+1-q) <= chunkRenderRadius {
+			l.detail[[2]int{tp, tq}] = true
+			return
+		}
+	} else if float64(dist) < float64(span)*lodSplit {
+		for i := 0; i < 4; i++ {
+			l.selectTile(level-1, 2*tp+i%2, 2*tq+i/2, p, q)
+		}
+		return
+	}
+	l.selected[[3]int{level, tp, tq}] = true
+}
+
+//chunkDistance returns the distance of p from the range of the chunks
+func chunkDistance(p, min, max int) int {
+	switch {
+	case p < min:
+		return min - p
+	case p > max:
+		return p - max
+	}
+	return 0
+}
+
+//newTile generates the heightmap mesh of the tile, the cells are the boxes of the sampled height
+//with the walls between the cells of different height and the skirts hiding the cracks
+//between the tiles of different levels
+func (l *lodTerrain) newTile(level, tp, tq int) (*lodTile, error) {
+	step := 1 << uint(level)
+	x0, z0 := tp*lodCells*step, tq*lodCells*step
+	var heights [lodCells + 2][lodCells + 2]int
+	var types [lodCells + 2][lodCells + 2]itemType
+	maxHeight := 0
+	for i := range heights {
+		for j := range heights[i] {
+			//the cell is sampled in its center
+			h, t := terrainHeight(x0+(i-1)*step+step/2, z0+(j-1)*step+step/2)
+			//the distant sea is drawn as the opaque surface
+			if h < seaLevel {
+				h, t = seaLevel, WaterItem
+			}
+			heights[i][j], types[i][j] = h, t
+			if h > maxHeight {
+				maxHeight = h
+			}
+		}
+	}
+	skirt := 2 * step
+	var vertices []float32
+	for i := 1; i <= lodCells; i++ {
+		for j := 1; j <= lodCells; j++ {
+			h, t := heights[i][j], types[i][j]
+			cellMin := mgl32.Vec3{float32(x0 + (i-1)*step), 0, float32(z0 + (j-1)*step)}
+			vertices = appendBoxFace(vertices, 1, cellMin, mgl32.Vec3{float32(step), float32(h), float32(step)}, t)
+			for f := 2; f < 6; f++ {
+				d := faceDirections[f]
+				ni, nj := i+d[0], j+d[2]
+				bottom := heights[ni][nj]
+				if ni == 0 || ni == lodCells+1 || nj == 0 || nj == lodCells+1 {
+					if h-skirt < bottom {
+						bottom = h - skirt
+					}
+				}
+				if bottom >= h {
+					continue
+				}
+				min := mgl32.Vec3{cellMin[0], float32(bottom), cellMin[2]}
+				vertices = appendBoxFace(vertices, f, min, mgl32.Vec3{float32(step), float32(h - bottom), float32(step)}, t)
+			}
+		}
+	}
+	tile := &lodTile{
+		level:    level,
+		tp:       tp,
+		tq:       tq,
+		vertices: int32(len(vertices) / chunkVertexSize),
+		min:      mgl32.Vec3{float32(x0), 0, float32(z0)},
+		max:      mgl32.Vec3{float32(x0 + lodCells*step), float32(maxHeight), float32(z0 + lodCells*step)},
+	}
+	tile.buffer = glw.NewBuffer(vertices)
+	tile.vao = glw.NewVertexArray()
+	if err := tile.vao.SetLayout(l.p, chunkLayout, tile.buffer); err != nil {
+		tile.delete()
+		return nil, err
+	}
+	return tile, nil
+}
+
+//appendBoxFace appends the vertices of the face f of the box with the texture of the block type
+func appendBoxFace(vertices []float32, f int, min, size mgl32.Vec3, t itemType) []float32 {
+	for i := 0; i < 6; i++ {
+		v := cubeVertices[f*6*3+i*3:]
+		uv := uvs[f*6*2+i*2:]
+		vertices = append(vertices,
+			min[0]+v[0]*size[0], min[1]+v[1]*size[1], min[2]+v[2]*size[2],
+			uv[0]+texWidth*float32(blocks[t].texture), uv[1],
+		)
+	}
+	return vertices
+}
+
+func (tile *lodTile) delete() {
+	tile.vao.Delete()
+	tile.buffer.Delete()
+}
+
+//Render draws the tiles in the frustum, the fading tiles are dithered
+func (l *lodTerrain) Render(projView mgl32.Mat4, alpha float64) {
+	frustum := l.w.cam.Frustum()
+	l.p.UseProgram()
+	l.w.texture.BindToUnit(0)
+	draw := func(tile *lodTile) {
+		if tile.vertices == 0 || !frustum.ContainsAABB(tile.min, tile.max) {
+			return
+		}
+		if err := l.p.SetFloat("fade", float32(tile.fade)); err != nil {
+			log.Println(err)
+		}
+		tile.vao.BindVertexArray()
+		gl.DrawArrays(gl.TRIANGLES, 0, tile.vertices)
+	}
+	for _, tile := range l.tiles {
+		draw(tile)
+	}
+	for _, tile := range l.retiring {
+		draw(tile)
+	}
+}
+
+//newChunkFade creates the texture of the dither levels of the chunks in the detail tiles around the player,
+//the world program reads it from chunkFadeUnit
+func newChunkFade(pp *glw.Preprocessor) (glw.Texture, *image.RGBA) {
+	pp.SetDefine("DETAIL_TILE_SIZE", strconv.Itoa(2*chunkSize))
+	levels := image.NewRGBA(image.Rect(0, 0, chunkFadeSize, chunkFadeSize))
+	texture := glw.NewTextureFromImage(levels, glw.TextureOptions{
+		MinFilter: gl.NEAREST,
+		MagFilter: gl.NEAREST,
+		WrapS:     gl.CLAMP_TO_EDGE,
+		WrapT:     gl.CLAMP_TO_EDGE,
+		Unit:      chunkFadeUnit,
+	})
+	return texture, levels
+}
+
+//updateChunkFade uploads the dither levels of the chunks around the player at the chunk p, q,
+//without the terrain tiles the chunks are drawn whole
+func (w *world) updateChunkFade(p, q int) {
+	tp, tq := floorDiv(p-chunkRenderRadius, 2), floorDiv(q-chunkRenderRadius, 2)
+	for i := 0; i < chunkFadeSize; i++ {
+		for j := 0; j < chunkFadeSize; j++ {
+			lo, hi := 0, 16
+			if w.lod != nil {
+				lo, hi = w.lod.chunkDitherLevels(tp+i, tq+j)
+			}
+			o := w.fadeLevels.PixOffset(i, j)
+			w.fadeLevels.Pix[o], w.fadeLevels.Pix[o+1] = uint8(lo), uint8(hi)
+		}
+	}
+	w.chunkFade.BindToUnit(chunkFadeUnit)
+	if err := w.chunkFade.SubImage(0, 0, w.fadeLevels); err != nil {
+		log.Println(err)
+	}
+	if err := w.p.SetIVec2("chunkFadeOrigin", [2]int32{int32(tp), int32(tq)}); err != nil {
+		log.Println(err)
+	}
+}
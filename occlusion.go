@@ -0,0 +1,191 @@
+package main
+
+import (
+	"math"
+
+	"github.com/microo8/craft/glw"
+)
+
+//the faces of the section in the order of the block faces: bottom, top, front, back, left, right,
+//the opposite face is face^1
+var faceDirections = [6][3]int{
+	{0, -1, 0},
+	{0, 1, 0},
+	{0, 0, 1},
+	{0, 0, -1},
+	{-1, 0, 0},
+	{1, 0, 0},
+}
+
+//sectionVisibility is the set of the pairs of the section faces which can see each other
+//through the non-opaque blocks of the section, bit a*6+b is set for connected faces a and b
+type sectionVisibility uint64
+
+//allVisible connects all faces, it's the visibility of the empty section
+const allVisible sectionVisibility = 1<<36 - 1
+
+func (v sectionVisibility) connected(a, b int) bool {
+	return v&(1<<uint(a*6+b)) != 0
+}
+
+//connect connects all pairs of the faces in the set
+func (v *sectionVisibility) connect(faces uint8) {
+	for a := 0; a < 6; a++ {
+		if faces&(1<<uint(a)) == 0 {
+			continue
+		}
+		for b := 0; b < 6; b++ {
+			if faces&(1<<uint(b)) != 0 {
+				*v |= 1 << uint(a*6+b)
+			}
+		}
+	}
+}
+
+//computeVisibility flood fills the non-opaque blocks of the section
+//and connects the faces touched by each filled region
+func (chunk *Chunk) computeVisibility(s int) sectionVisibility {
+	const n = chunkSize * sectionHeight * chunkSize
+	index := func(x, y, z int) int { return (x*sectionHeight+y)*chunkSize + z }
+	var visited [n]bool
+	empty := 0
+	for x := 0; x < chunkSize; x++ {
+		for y := 0; y < sectionHeight; y++ {
+			for z := 0; z < chunkSize; z++ {
+				if opaque(chunk.m[x][s*sectionHeight+y][z].t) {
+					visited[index(x, y, z)] = true
+				} else {
+					empty++
+				}
+			}
+		}
+	}
+	if empty == n {
+		return allVisible
+	}
+	if empty == 0 {
+		return 0
+	}
+	var v sectionVisibility
+	stack := make([][3]int, 0, 256)
+	for start := 0; start < n; start++ {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		stack = append(stack[:0], [3]int{start / (sectionHeight * chunkSize), start / chunkSize % sectionHeight, start % chunkSize})
+		var faces uint8
+		for len(stack) > 0 {
+			b := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for f, d := range faceDirections {
+				x, y, z := b[0]+d[0], b[1]+d[1], b[2]+d[2]
+				if x < 0 || x >= chunkSize || y < 0 || y >= sectionHeight || z < 0 || z >= chunkSize {
+					faces |= 1 << uint(f)
+					continue
+				}
+				if i := index(x, y, z); !visited[i] {
+					visited[i] = true
+					stack = append(stack, [3]int{x, y, z})
+				}
+			}
+		}
+		v.connect(faces)
+	}
+	return v
+}
+
+//sectionNode is the section reached by the visibility search
+type sectionNode struct {
+	chunk *Chunk
+	s     int
+	from  int   //face the section was entered through, -1 for the camera section
+	dirs  uint8 //directions travelled from the camera section
+}
+
+//visibleSections finds the sections potentially visible from the camera by searching through
+//the connected faces of the sections in the frustum, the meshes are collected in w.visible by the render pass
+//and the translucent ones in w.translucent with their distance
+func (w *world) visibleSections(frustum glw.Frustum) {
+	const size = 2*chunkRenderRadius + 1
+	if w.reached == nil {
+		w.reached = make([]bool, size*size*chunkSections)
+	}
+	for i := range w.reached {
+		w.reached[i] = false
+	}
+	p0 := floorDiv(int(math.Floor(float64(w.cam.Pos[0]))), chunkSize)
+	q0 := floorDiv(int(math.Floor(float64(w.cam.Pos[2]))), chunkSize)
+	s0 := int(math.Floor(float64(w.cam.Pos[1]))) / sectionHeight
+	if w.cam.Pos[1] < 0 {
+		s0 = 0
+	}
+	if s0 >= chunkSections {
+		s0 = chunkSections - 1
+	}
+	reached := func(p, q, s int) *bool {
+		return &w.reached[((p-p0+chunkRenderRadius)*size+q-q0+chunkRenderRadius)*chunkSections+s]
+	}
+
+	start := w.getChunk(p0, q0)
+	if start == nil {
+		return
+	}
+	*reached(p0, q0, s0) = true
+	queue := append(w.queue[:0], sectionNode{chunk: start, s: s0, from: -1})
+	for i := 0; i < len(queue); i++ {
+		node := queue[i]
+		if node.chunk.hasMesh(node.s) && w.fullDetail(node.chunk.P, node.chunk.Q) {
+			w.drawn++
+			for pass, mesh := range node.chunk.sections[node.s] {
+				if mesh == nil {
+					continue
+				}
+				if renderPass(pass) == translucentPass {
+					min, max := node.chunk.sectionBounds(node.s)
+					center := min.Add(max).Mul(0.5)
+					w.translucent = append(w.translucent, translucentSection{mesh, center.Sub(w.cam.Pos).Len()})
+					continue
+				}
+				w.visible[pass] = append(w.visible[pass], mesh)
+			}
+		}
+		for f, d := range faceDirections {
+			//never go back towards the camera
+			if node.dirs&(1<<uint(f^1)) != 0 {
+				continue
+			}
+			if node.from >= 0 && !node.chunk.visibility[node.s].connected(node.from, f) {
+				continue
+			}
+			p, q, s := node.chunk.P+d[0], node.chunk.Q+d[2], node.s+d[1]
+			if s < 0 || s >= chunkSections || abs(p-p0) > chunkRenderRadius || abs(q-q0) > chunkRenderRadius {
+				continue
+			}
+			r := reached(p, q, s)
+			if *r {
+				continue
+			}
+			*r = true
+			chunk := node.chunk
+			if p != chunk.P || q != chunk.Q {
+				if chunk = w.getChunk(p, q); chunk == nil {
+					continue
+				}
+			}
+			if !frustum.ContainsAABB(chunk.sectionBounds(s)) {
+				if chunk.hasMesh(s) && w.fullDetail(p, q) {
+					w.culled++
+				}
+				continue
+			}
+			queue = append(queue, sectionNode{chunk: chunk, s: s, from: f ^ 1, dirs: node.dirs | 1<<uint(f)})
+		}
+	}
+	w.queue = queue
+}
+
+//fullDetail reports whether the chunk is rendered with the voxels instead of the terrain tile
+func (w *world) fullDetail(p, q int) bool {
+	return w.lod == nil || w.lod.fullDetail(p, q)
+}
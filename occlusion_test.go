@@ -0,0 +1,178 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/microo8/craft/glw"
+)
+
+//face indices of faceDirections
+const (
+	bottomFace = iota
+	topFace
+	frontFace
+	backFace
+	leftFace
+	rightFace
+)
+
+//newTestChunk creates the chunk filled with the blocks of type t
+func newTestChunk(w *world, p, q int, t itemType) *Chunk {
+	chunk := &Chunk{P: p, Q: q, w: w}
+	for x := range chunk.m {
+		for y := range chunk.m[x] {
+			for z := range chunk.m[x][y] {
+				chunk.m[x][y][z] = &Block{t: t}
+			}
+		}
+	}
+	return chunk
+}
+
+//digTunnel empties the blocks of the section at y 8 and z 8 along the x axis
+func digTunnel(chunk *Chunk, s int) {
+	for x := 0; x < chunkSize; x++ {
+		chunk.m[x][s*sectionHeight+8][8].t = EmptyItem
+	}
+}
+
+func TestComputeVisibility(t *testing.T) {
+	const s = 2
+	tests := []struct {
+		name  string
+		fill  itemType
+		dig   func(chunk *Chunk)
+		faces []int //faces connected through the dug blocks
+	}{
+		{name: "empty", fill: EmptyItem},
+		{name: "solid", fill: StoneItem},
+		{name: "transparent", fill: GlassItem},
+		{name: "tunnel", fill: StoneItem, dig: func(chunk *Chunk) { digTunnel(chunk, s) }, faces: []int{leftFace, rightFace}},
+		{
+			name: "closed cave",
+			fill: StoneItem,
+			dig: func(chunk *Chunk) {
+				for x := 4; x < 12; x++ {
+					chunk.m[x][s*sectionHeight+8][8].t = EmptyItem
+				}
+			},
+		},
+		{
+			name: "shaft and tunnel",
+			fill: StoneItem,
+			dig: func(chunk *Chunk) {
+				digTunnel(chunk, s)
+				for y := 0; y < sectionHeight; y++ {
+					chunk.m[8][s*sectionHeight+y][8].t = EmptyItem
+				}
+			},
+			faces: []int{bottomFace, topFace, leftFace, rightFace},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chunk := newTestChunk(nil, 0, 0, test.fill)
+			if test.dig != nil {
+				test.dig(chunk)
+			}
+			v := chunk.computeVisibility(s)
+			want := map[[2]int]bool{}
+			if !opaque(test.fill) {
+				for a := 0; a < 6; a++ {
+					for b := 0; b < 6; b++ {
+						want[[2]int{a, b}] = true
+					}
+				}
+			}
+			for _, a := range test.faces {
+				for _, b := range test.faces {
+					want[[2]int{a, b}] = true
+				}
+			}
+			for a := 0; a < 6; a++ {
+				for b := 0; b < 6; b++ {
+					if v.connected(a, b) != want[[2]int{a, b}] {
+						t.Errorf("faces %d and %d connected %v, want %v", a, b, v.connected(a, b), want[[2]int{a, b}])
+					}
+				}
+			}
+		})
+	}
+}
+
+//newTestWorld creates the row of the chunks along the x axis with one opaque mesh in every section of the row
+//and the camera in the middle of the section s of the first chunk looking along the x axis,
+//the sections of the chunks are empty except the solid section s of the middle chunk
+func newTestWorld(s int, tunnel bool) (*world, glw.Frustum) {
+	cam := &glw.Camera{Pos: mgl32.Vec3{8, float32(s*sectionHeight + 8), 8}}
+	w := &world{cam: cam, chunks: make(map[[2]int]*Chunk)}
+	for p := 0; p < 3; p++ {
+		chunk := newTestChunk(w, p, 0, EmptyItem)
+		if p == 1 {
+			for x := 0; x < chunkSize; x++ {
+				for y := 0; y < sectionHeight; y++ {
+					for z := 0; z < chunkSize; z++ {
+						chunk.m[x][s*sectionHeight+y][z].t = StoneItem
+					}
+				}
+			}
+			if tunnel {
+				digTunnel(chunk, s)
+			}
+		}
+		for i := range chunk.sections {
+			chunk.sections[i][opaquePass] = &glw.ArenaMesh{}
+			chunk.visibility[i] = chunk.computeVisibility(i)
+		}
+		w.chunks[[2]int{p, 0}] = chunk
+	}
+	proj := mgl32.Perspective(mgl32.DegToRad(90), 1, 0.1, 200)
+	view := mgl32.LookAtV(cam.Pos, cam.Pos.Add(mgl32.Vec3{1, 0, 0}), mgl32.Vec3{0, 1, 0})
+	return w, glw.NewFrustum(proj.Mul4(view))
+}
+
+//drawnSection reports whether the mesh of the section was collected by the visibility search
+func drawnSection(w *world, p, s int) bool {
+	mesh := w.chunks[[2]int{p, 0}].sections[s][opaquePass]
+	for _, m := range w.visible[opaquePass] {
+		if m == mesh {
+			return true
+		}
+	}
+	return false
+}
+
+func TestVisibleSections(t *testing.T) {
+	const s = 4
+	tests := []struct {
+		name   string
+		tunnel bool
+		behind bool //the section behind the solid section is visible
+	}{
+		{name: "solid section", tunnel: false, behind: false},
+		{name: "tunnel", tunnel: true, behind: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, frustum := newTestWorld(s, test.tunnel)
+			w.visibleSections(frustum)
+			if !drawnSection(w, 0, s) {
+				t.Error("camera section isn't drawn")
+			}
+			if !drawnSection(w, 1, s) {
+				t.Error("solid section in front of the camera isn't drawn")
+			}
+			if drawnSection(w, 2, s) != test.behind {
+				t.Errorf("section behind the solid section drawn %v, want %v", drawnSection(w, 2, s), test.behind)
+			}
+			//the empty sections above are seen over the solid one
+			if !drawnSection(w, 2, s+1) {
+				t.Error("empty section above isn't drawn")
+			}
+			if w.drawn != len(w.visible[opaquePass]) {
+				t.Errorf("%d sections counted as drawn, %d meshes collected", w.drawn, len(w.visible[opaquePass]))
+			}
+		})
+	}
+}
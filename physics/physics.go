@@ -0,0 +1,164 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	//Timestep is the fixed duration of one simulation step in seconds
+	Timestep = 1.0 / 60
+	//Gravity is the downward acceleration in blocks per second squared
+	Gravity = 28
+	//TerminalVelocity is the max falling speed in blocks per second
+	TerminalVelocity = 60
+	//JumpVelocity is the upward speed at the start of a jump
+	JumpVelocity = 8.5
+	//StepHeight is the max height of a ledge the body walks onto without jumping
+	StepHeight = 1
+
+	//epsilon keeps the boxes from snagging on the faces they are touching
+	epsilon = 1e-4
+)
+
+//World is queried for the blocks the bodies collide with
+type World interface {
+	Solid(x, y, z int) bool
+}
+
+//AABB axis aligned bounding box
+type AABB struct {
+	Min, Max mgl32.Vec3
+}
+
+//Offset returns the box moved by v
+func (b AABB) Offset(v mgl32.Vec3) AABB {
+	return AABB{Min: b.Min.Add(v), Max: b.Max.Add(v)}
+}
+
+//Intersects reports whether the boxes overlap
+func (b AABB) Intersects(o AABB) bool {
+	for i := 0; i < 3; i++ {
+		if b.Min[i] >= o.Max[i] || b.Max[i] <= o.Min[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//BlockBox returns the box of the block at the position
+func BlockBox(x, y, z int) AABB {
+	return AABB{
+		Min: mgl32.Vec3{float32(x), float32(y), float32(z)},
+		Max: mgl32.Vec3{float32(x + 1), float32(y + 1), float32(z + 1)},
+	}
+}
+
+//Body is a box moving trough the world and colliding with its solid blocks
+type Body struct {
+	Pos      mgl32.Vec3 //center of the bottom face
+	Vel      mgl32.Vec3
+	Size     mgl32.Vec3 //width, height and depth of the box
+	OnGround bool
+	Fly      bool //disables gravity and collisions
+}
+
+//Box returns the bounding box of the body at its current position
+func (b *Body) Box() AABB {
+	half := mgl32.Vec3{b.Size[0] / 2, 0, b.Size[2] / 2}
+	return AABB{
+		Min: b.Pos.Sub(half),
+		Max: b.Pos.Add(half).Add(mgl32.Vec3{0, b.Size[1], 0}),
+	}
+}
+
+//Step advances the body by dt seconds
+//wish is the velocity the body wants to move with, only the horizontal part is used unless flying
+func (b *Body) Step(w World, dt float32, wish mgl32.Vec3, jump bool) {
+	if b.Fly {
+		b.Vel = wish
+		b.Pos = b.Pos.Add(wish.Mul(dt))
+		b.OnGround = false
+		return
+	}
+	b.Vel[0] = wish[0]
+	b.Vel[2] = wish[2]
+	if jump && b.OnGround {
+		b.Vel[1] = JumpVelocity
+	}
+	b.Vel[1] -= Gravity * dt
+	if b.Vel[1] < -TerminalVelocity {
+		b.Vel[1] = -TerminalVelocity
+	}
+
+	delta := b.Vel.Mul(dt)
+	if moved := b.move(w, 1, delta[1]); moved != delta[1] {
+		b.OnGround = delta[1] < 0
+		b.Vel[1] = 0
+	} else {
+		b.OnGround = false
+	}
+	for _, axis := range [2]int{0, 2} {
+		moved := b.move(w, axis, delta[axis])
+		if moved == delta[axis] {
+			continue
+		}
+		if !b.OnGround || !b.stepUp(w, axis, delta[axis]-moved) {
+			b.Vel[axis] = 0
+		}
+	}
+}
+
+//stepUp tries to climb a ledge blocking the movement along the horizontal axis
+func (b *Body) stepUp(w World, axis int, d float32) bool {
+	start := b.Pos
+	up := b.move(w, 1, StepHeight)
+	if moved := b.move(w, axis, d); moved == 0 {
+		b.Pos = start
+		return false
+	}
+	b.move(w, 1, -up)
+	return true
+}
+
+//move moves the body along the axis by d or until it touches a solid block
+//and returns the distance it moved
+func (b *Body) move(w World, axis int, d float32) float32 {
+	if d == 0 {
+		return 0
+	}
+	box := b.Box()
+	swept := box
+	if d > 0 {
+		swept.Max[axis] += d
+	} else {
+		swept.Min[axis] += d
+	}
+	var min, max [3]int
+	for i := 0; i < 3; i++ {
+		min[i] = int(math.Floor(float64(swept.Min[i] + epsilon)))
+		max[i] = int(math.Floor(float64(swept.Max[i] - epsilon)))
+	}
+	for x := min[0]; x <= max[0]; x++ {
+		for y := min[1]; y <= max[1]; y++ {
+			for z := min[2]; z <= max[2]; z++ {
+				if !w.Solid(x, y, z) {
+					continue
+				}
+				cell := BlockBox(x, y, z)
+				if d > 0 && cell.Min[axis] >= box.Max[axis]-epsilon {
+					if dist := cell.Min[axis] - box.Max[axis]; dist < d {
+						d = float32(math.Max(float64(dist), 0))
+					}
+				} else if d < 0 && cell.Max[axis] <= box.Min[axis]+epsilon {
+					if dist := cell.Max[axis] - box.Min[axis]; dist > d {
+						d = float32(math.Min(float64(dist), 0))
+					}
+				}
+			}
+		}
+	}
+	b.Pos[axis] += d
+	return d
+}
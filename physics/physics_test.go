@@ -0,0 +1,161 @@
+package physics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+//blocks is the world of the solid blocks set in the map, y < 0 is the solid floor
+type blocks map[[3]int]bool
+
+func (b blocks) Solid(x, y, z int) bool {
+	return y < 0 || b[[3]int{x, y, z}]
+}
+
+func newBody(x, y, z float32) *Body {
+	return &Body{Pos: mgl32.Vec3{x, y, z}, Size: mgl32.Vec3{0.6, 1.8, 0.6}}
+}
+
+//run steps the body for the seconds with the same wish and jump
+func run(b *Body, w World, seconds float32, wish mgl32.Vec3, jump bool) {
+	for i := 0; i < int(seconds/Timestep); i++ {
+		b.Step(w, Timestep, wish, jump)
+	}
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func TestFallAndLand(t *testing.T) {
+	b := newBody(0.5, 10, 0.5)
+	b.Step(blocks{}, Timestep, mgl32.Vec3{}, false)
+	if b.OnGround || b.Vel[1] >= 0 {
+		t.Fatalf("body in the air isn't falling: vel %v, on ground %v", b.Vel, b.OnGround)
+	}
+	run(b, blocks{}, 2, mgl32.Vec3{}, false)
+	if !near(b.Pos[1], 0) || !b.OnGround || b.Vel[1] != 0 {
+		t.Errorf("body didn't land on the floor: pos %v, vel %v, on ground %v", b.Pos, b.Vel, b.OnGround)
+	}
+}
+
+func TestTerminalVelocity(t *testing.T) {
+	b := newBody(0.5, 1000, 0.5)
+	run(b, blocks{}, 5, mgl32.Vec3{}, false)
+	if b.Vel[1] < -TerminalVelocity {
+		t.Errorf("falling speed %f exceeds the terminal velocity", -b.Vel[1])
+	}
+}
+
+func TestWallSliding(t *testing.T) {
+	w := blocks{}
+	for y := 0; y < 3; y++ {
+		for z := -5; z < 10; z++ {
+			w[[3]int{2, y, z}] = true
+		}
+	}
+	b := newBody(0.5, 0, 0.5)
+	run(b, w, 1, mgl32.Vec3{4, 0, 4}, false)
+	if b.Box().Max[0] > 2+1e-3 {
+		t.Errorf("body moved into the wall: box %v", b.Box())
+	}
+	if !near(b.Box().Max[0], 2) {
+		t.Errorf("body isn't touching the wall: box %v", b.Box())
+	}
+	if b.Pos[2] < 4 {
+		t.Errorf("body didn't slide along the wall: pos %v", b.Pos)
+	}
+}
+
+func TestStepUp(t *testing.T) {
+	w := blocks{}
+	for x := 2; x < 10; x++ {
+		for z := -5; z < 5; z++ {
+			w[[3]int{x, 0, z}] = true
+		}
+	}
+	b := newBody(0.5, 0, 0.5)
+	run(b, w, 1, mgl32.Vec3{4, 0, 0}, false)
+	if !near(b.Pos[1], 1) || b.Pos[0] < 3 {
+		t.Errorf("body didn't step up the ledge: pos %v", b.Pos)
+	}
+}
+
+func TestNoStepUpWall(t *testing.T) {
+	w := blocks{}
+	for y := 0; y < 2; y++ {
+		for z := -5; z < 5; z++ {
+			w[[3]int{2, y, z}] = true
+		}
+	}
+	b := newBody(0.5, 0, 0.5)
+	run(b, w, 1, mgl32.Vec3{4, 0, 0}, false)
+	if !near(b.Pos[1], 0) || b.Box().Max[0] > 2+1e-3 {
+		t.Errorf("body climbed the wall higher than a step: pos %v", b.Pos)
+	}
+}
+
+func TestJump(t *testing.T) {
+	b := newBody(0.5, 0, 0.5)
+	run(b, blocks{}, 0.1, mgl32.Vec3{}, false)
+	if !b.OnGround {
+		t.Fatal("body isn't on the ground")
+	}
+	b.Step(blocks{}, Timestep, mgl32.Vec3{}, true)
+	if b.OnGround || b.Vel[1] <= 0 {
+		t.Fatalf("body didn't jump: vel %v", b.Vel)
+	}
+	var top float32
+	for i := 0; i < 120; i++ {
+		b.Step(blocks{}, Timestep, mgl32.Vec3{}, false)
+		if b.Pos[1] > top {
+			top = b.Pos[1]
+		}
+	}
+	//the height of the jump is v²/2g
+	if want := JumpVelocity * JumpVelocity / (2 * Gravity); math.Abs(float64(top)-want) > 0.2 {
+		t.Errorf("jump height %f, want about %f", top, want)
+	}
+	if !near(b.Pos[1], 0) || !b.OnGround {
+		t.Errorf("body didn't land after the jump: pos %v", b.Pos)
+	}
+}
+
+func TestJumpInAir(t *testing.T) {
+	b := newBody(0.5, 10, 0.5)
+	b.Step(blocks{}, Timestep, mgl32.Vec3{}, true)
+	if b.Vel[1] > 0 {
+		t.Errorf("body jumped in the air: vel %v", b.Vel)
+	}
+}
+
+func TestFly(t *testing.T) {
+	w := blocks{{0, 3, 0}: true}
+	b := newBody(0.5, 0, 0.5)
+	b.Fly = true
+	run(b, w, 1, mgl32.Vec3{0, 5, 0}, false)
+	if b.Pos[1] < 4.9 || b.OnGround {
+		t.Errorf("flying body didn't pass through the block: pos %v", b.Pos)
+	}
+	//no gravity
+	run(b, w, 1, mgl32.Vec3{}, false)
+	if b.Pos[1] < 4.9 || b.Vel != (mgl32.Vec3{}) {
+		t.Errorf("flying body fell: pos %v, vel %v", b.Pos, b.Vel)
+	}
+	run(b, w, 2, mgl32.Vec3{0, -5, 0}, false)
+	if b.Pos[1] > -4.9 {
+		t.Errorf("flying body collided with the floor: pos %v", b.Pos)
+	}
+}
+
+func TestBlockBoxIntersects(t *testing.T) {
+	b := newBody(0.5, 0, 0.5)
+	if !BlockBox(0, 1, 0).Intersects(b.Box()) {
+		t.Error("block at the head doesn't intersect the body")
+	}
+	if BlockBox(0, -1, 0).Intersects(b.Box()) || BlockBox(1, 0, 0).Intersects(b.Box()) {
+		t.Error("touching block intersects the body")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/microo8/craft/glw"
+	"github.com/microo8/craft/physics"
+)
+
+const (
+	playerWalkSpeed = 4.3
+	playerFlySpeed  = 10
+	playerEyeHeight = 1.62
+)
+
+//Player is the body controlled by the user, the camera follows its eyes
+type Player struct {
+	body    physics.Body
+	prevPos mgl32.Vec3
+	cam     *glw.Camera
+	input   *glw.Input
+}
+
+func newPlayer(cam *glw.Camera, input *glw.Input) *Player {
+	pos := cam.Pos.Sub(mgl32.Vec3{0, playerEyeHeight, 0})
+	return &Player{
+		body: physics.Body{
+			Pos:  pos,
+			Size: mgl32.Vec3{0.6, 1.8, 0.6},
+		},
+		prevPos: pos,
+		cam:     cam,
+		input:   input,
+	}
+}
+
+//Chunk returns the position of the chunk the player is standing in
+func (pl *Player) Chunk() (p, q int) {
+	return floorDiv(int(math.Floor(float64(pl.body.Pos[0]))), chunkSize),
+		floorDiv(int(math.Floor(float64(pl.body.Pos[2]))), chunkSize)
+}
+
+//Update runs one physics step
+func (pl *Player) Update(w physics.World, dt float64) {
+	if pl.input.JustPressed("fly") {
+		pl.body.Fly = !pl.body.Fly
+		pl.body.Vel = mgl32.Vec3{}
+	}
+	pl.prevPos = pl.body.Pos
+	pl.body.Step(w, float32(dt), pl.wish(), pl.input.IsDown("jump"))
+}
+
+//Eye returns the position of the eyes after the last physics step
+func (pl *Player) Eye() mgl32.Vec3 {
+	return pl.body.Pos.Add(mgl32.Vec3{0, playerEyeHeight, 0})
+}
+
+//Interpolate moves the camera to the eye position between the last two physics steps
+func (pl *Player) Interpolate(alpha float64) {
+	a := float32(alpha)
+	pos := pl.prevPos.Mul(1 - a).Add(pl.body.Pos.Mul(a))
+
+	direction := pl.cam.Rotation.Sub(pl.cam.Pos)
+	pl.cam.Pos = pos.Add(mgl32.Vec3{0, playerEyeHeight, 0})
+	pl.cam.Rotation = pl.cam.Pos.Add(direction)
+	pl.cam.Update()
+}
+
+//wish returns the velocity requested by the held movement actions
+func (pl *Player) wish() mgl32.Vec3 {
+	direction := pl.cam.Rotation.Sub(pl.cam.Pos)
+	speed := float32(playerWalkSpeed)
+	if pl.body.Fly {
+		speed = playerFlySpeed
+	} else {
+		direction[1] = 0
+	}
+	if direction.Len() == 0 {
+		return mgl32.Vec3{}
+	}
+	forward := direction.Normalize()
+	right := forward.Cross(mgl32.Vec3{0, 1, 0})
+	var wish mgl32.Vec3
+	if pl.input.IsDown("forward") {
+		wish = wish.Add(forward)
+	}
+	if pl.input.IsDown("back") {
+		wish = wish.Sub(forward)
+	}
+	if pl.input.IsDown("right") {
+		wish = wish.Add(right)
+	}
+	if pl.input.IsDown("left") {
+		wish = wish.Sub(right)
+	}
+	if pl.body.Fly {
+		if pl.input.IsDown("jump") {
+			wish[1]++
+		}
+		if pl.input.IsDown("down") {
+			wish[1]--
+		}
+	}
+	if wish.Len() == 0 {
+		return wish
+	}
+	return wish.Normalize().Mul(speed)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/microo8/craft/glw"
+)
+
+//postPasses returns the passes of the post-processing chain in the default order,
+//the bloom works with the high dynamic range so it's before the tone mapping
+func postPasses() []*glw.PostPass {
+	return []*glw.PostPass{
+		{
+			Name:    "bloom",
+			Enabled: true,
+			Params:  map[string]float32{"threshold": 0.9, "intensity": 0.6, "radius": 1.5},
+			Steps: []glw.PostStep{
+				{Shader: "post_bloom_extract.glsl"},
+				{Shader: "post_blur.glsl", Params: map[string]float32{"dirX": 1}},
+				{Shader: "post_blur.glsl", Params: map[string]float32{"dirY": 1}},
+				{Shader: "post_bloom_combine.glsl"},
+			},
+		},
+		{
+			Name:    "tonemap",
+			Enabled: true,
+			Params:  map[string]float32{"exposure": 1.25},
+			Steps:   []glw.PostStep{{Shader: "post_tonemap.glsl"}},
+		},
+		{
+			Name:    "fxaa",
+			Enabled: true,
+			Steps:   []glw.PostStep{{Shader: "post_fxaa.glsl"}},
+		},
+		{
+			//the world skips it when the camera isn't in the water
+			Name:    "underwater",
+			Enabled: true,
+			Params:  map[string]float32{"strength": 1},
+			Steps:   []glw.PostStep{{Shader: "post_underwater.glsl"}},
+			Skip:    true,
+		},
+		{
+			Name:    "vignette",
+			Enabled: true,
+			Params:  map[string]float32{"strength": 0.3, "radius": 0.6},
+			Steps:   []glw.PostStep{{Shader: "post_vignette.glsl"}},
+		},
+		{
+			//the shaders output the display colours, it's for the scene rendered in the linear space
+			Name:    "gamma",
+			Enabled: false,
+			Params:  map[string]float32{"gamma": 2.2},
+			Steps:   []glw.PostStep{{Shader: "post_gamma.glsl"}},
+		},
+	}
+}
+
+//newPostChain creates the post-processing chain of the app with the passes configured by the settings
+func newPostChain(app *glw.App, configs []glw.PostPassConfig) (*glw.PostChain, error) {
+	post, err := app.NewPostChain(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, pass := range postPasses() {
+		if err := post.Add(pass); err != nil {
+			return nil, err
+		}
+	}
+	if err := post.Configure(configs); err != nil {
+		log.Println(err)
+	}
+	return post, nil
+}
+
+//registerPostCommands adds the post command listing and changing the post-processing passes
+func registerPostCommands(c *console, post *glw.PostChain) {
+	c.Register("post", "post [on|off <pass>|move <pass> <index>|set <pass> <param> <value>] - lists or changes the post-processing passes", func(args []string) error {
+		if len(args) == 0 {
+			for i, pass := range post.Passes() {
+				state := "off"
+				if pass.Enabled {
+					state = "on"
+				}
+				params := make([]string, 0, len(pass.Params))
+				for name, v := range pass.Params {
+					params = append(params, fmt.Sprintf("%s=%g", name, v))
+				}
+				sort.Strings(params)
+				log.Printf("%d %s %s %s", i, pass.Name, state, strings.Join(params, " "))
+			}
+			return nil
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("wrong number of arguments")
+		}
+		pass := post.Pass(args[1])
+		if pass == nil {
+			return fmt.Errorf("unknown post pass %s", args[1])
+		}
+		switch {
+		case args[0] == "on" && len(args) == 2:
+			pass.Enabled = true
+		case args[0] == "off" && len(args) == 2:
+			pass.Enabled = false
+		case args[0] == "move" && len(args) == 3:
+			index, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("invalid index %s", args[2])
+			}
+			return post.Move(pass.Name, index)
+		case args[0] == "set" && len(args) == 4:
+			if _, ok := pass.Params[args[2]]; !ok {
+				return fmt.Errorf("post pass %s has no param %s", pass.Name, args[2])
+			}
+			v, err := strconv.ParseFloat(args[3], 32)
+			if err != nil {
+				return fmt.Errorf("invalid value %s", args[3])
+			}
+			pass.Params[args[2]] = float32(v)
+		default:
+			return fmt.Errorf("unknown subcommand %s", args[0])
+		}
+		return nil
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+//blockReach is the max distance in blocks the player can target
+const blockReach = 6
+
+//RaycastHit is the block hit by a ray
+type RaycastHit struct {
+	Block    [3]int //world position of the hit block
+	Normal   [3]int //normal of the hit face, zero if the ray started inside the block
+	Distance float32
+}
+
+//Raycast walks trough the voxels along the ray (Amanatides & Woo DDA)
+//and returns the first non empty and non liquid block closer than maxDist
+func (w *world) Raycast(origin, dir mgl32.Vec3, maxDist float32) (hit RaycastHit, ok bool) {
+	if dir.Len() == 0 {
+		return hit, false
+	}
+	dir = dir.Normalize()
+	var pos, step [3]int
+	var tMax, tDelta [3]float32
+	for i := 0; i < 3; i++ {
+		pos[i] = int(math.Floor(float64(origin[i])))
+		switch {
+		case dir[i] > 0:
+			step[i] = 1
+			tMax[i] = (float32(pos[i]+1) - origin[i]) / dir[i]
+			tDelta[i] = 1 / dir[i]
+		case dir[i] < 0:
+			step[i] = -1
+			tMax[i] = (float32(pos[i]) - origin[i]) / dir[i]
+			tDelta[i] = -1 / dir[i]
+		default:
+			tMax[i] = float32(math.Inf(1))
+			tDelta[i] = float32(math.Inf(1))
+		}
+	}
+	var t float32
+	for t <= maxDist {
+		//the liquids are seen through
+		if !replaceable(w.GetBlock(pos[0], pos[1], pos[2])) {
+			hit.Block = pos
+			hit.Distance = t
+			return hit, true
+		}
+		axis := 0
+		if tMax[1] < tMax[axis] {
+			axis = 1
+		}
+		if tMax[2] < tMax[axis] {
+			axis = 2
+		}
+		t = tMax[axis]
+		pos[axis] += step[axis]
+		tMax[axis] += tDelta[axis]
+		hit.Normal = [3]int{}
+		hit.Normal[axis] = -step[axis]
+	}
+	return RaycastHit{}, false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/microo8/craft/glw"
+)
+
+const (
+	//defaultSettingsPath is the config file of the default graphics settings, it isn't changed by the game
+	defaultSettingsPath = "assets/config/settings.json"
+	//settingsPath is the file the changed settings are saved to, it's loaded instead of the defaults
+	settingsPath = "save/settings.json"
+)
+
+//settings are the graphics settings, the console changes them at runtime and saves them
+type settings struct {
+	Shadows string               `json:"shadows"`
+	Post    []glw.PostPassConfig `json:"post"` //order, state and parameters of the post-processing passes
+}
+
+//loadSettings loads the saved settings or the default ones when they weren't saved yet
+func loadSettings() (settings, error) {
+	s, err := readSettings(settingsPath)
+	if os.IsNotExist(err) {
+		return readSettings(defaultSettingsPath)
+	}
+	return s, err
+}
+
+func readSettings(path string) (settings, error) {
+	s := settings{Shadows: shadowHigh.String()}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("cannot decode settings %s: %s", path, err)
+	}
+	return s, nil
+}
+
+func saveSettings(path string, s settings) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+//registerSettingsCommands adds the settings command saving the current settings
+func registerSettingsCommands(c *console, w *world, post *glw.PostChain) {
+	c.Register("settings", "settings save - saves the current settings into "+settingsPath, func(args []string) error {
+		if len(args) != 1 || args[0] != "save" {
+			return fmt.Errorf("unknown subcommand")
+		}
+		s := settings{Shadows: w.shadows.quality.String(), Post: post.Config()}
+		if err := saveSettings(settingsPath, s); err != nil {
+			return err
+		}
+		log.Println("settings saved to", settingsPath)
+		return nil
+	})
+}
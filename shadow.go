@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/microo8/craft/glw"
+)
+
+const (
+	//shadowBinding is the binding point of the shadow uniform buffer
+	shadowBinding = 1
+	//shadowUnit is the texture unit of the shadow map
+	shadowUnit = 1
+	//shadowMaxCascades is the size of the arrays in the Shadows uniform block
+	shadowMaxCascades = 4
+	//shadowDistance is the distance covered by the cascades, only the chunks cast the shadows
+	shadowDistance = chunkRenderRadius * chunkSize
+	//shadowCasterDistance is how far towards the sun the casters are rendered
+	shadowCasterDistance = chunkSections * sectionHeight
+	//shadowSplitLambda blends the uniform and the logarithmic cascade splits
+	shadowSplitLambda = 0.75
+)
+
+//shadowQuality is the quality of the sun shadows
+type shadowQuality int
+
+const (
+	shadowOff shadowQuality = iota
+	shadowLow
+	shadowHigh
+)
+
+var shadowQualityNames = [...]string{"off", "low", "high"}
+
+//shadowSettings are the numbers of the cascades, the size of the shadow map
+//and the radius of the PCF kernel of the qualities
+var shadowSettings = [...]struct {
+	cascades int
+	size     int
+	pcf      int
+}{
+	shadowOff:  {},
+	shadowLow:  {cascades: 2, size: 1024, pcf: 0},
+	shadowHigh: {cascades: 4, size: 2048, pcf: 2},
+}
+
+func (q shadowQuality) String() string {
+	return shadowQualityNames[q]
+}
+
+func parseShadowQuality(s string) (shadowQuality, error) {
+	for q, name := range shadowQualityNames {
+		if name == s {
+			return shadowQuality(q), nil
+		}
+	}
+	return shadowOff, fmt.Errorf("unknown shadow quality %s, use off, low or high", s)
+}
+
+//shadowData is the Shadows uniform block in assets/shaders/shadow.glsl
+type shadowData struct {
+	Matrices [shadowMaxCascades]mgl32.Mat4 //world to shadow map texture coordinates
+	Splits   mgl32.Vec4                    //far view depths of the cascades
+	Bias     mgl32.Vec4                    //normal offsets of the cascades
+	ViewDir  mgl32.Vec3
+}
+
+//shadowMap renders the depth of the chunks seen from the sun into the layers of the texture array,
+//one layer per cascade, the cascades cover the growing ranges of the view distance
+type shadowMap struct {
+	w       *world
+	quality shadowQuality
+
+	shader  *glw.ShaderProgram
+	p       glw.Program
+	fb      *glw.Framebuffer //nil when the shadows are off
+	data    *glw.UniformBuffer
+	casters []*glw.ArenaMesh
+}
+
+func newShadowMap(w *world, quality shadowQuality) *shadowMap {
+	return &shadowMap{w: w, quality: quality}
+}
+
+//init creates the depth program, the uniform buffer and the shadow map
+func (s *shadowMap) init() error {
+	shader, err := glw.NewShaderProgram(nil,
+		glw.ShaderFile{Type: gl.VERTEX_SHADER, Path: "shadow_vertex_shader.glsl"},
+		glw.ShaderFile{Type: gl.FRAGMENT_SHADER, Path: "shadow_fragment_shader.glsl"},
+	)
+	if err != nil {
+		return err
+	}
+	s.shader = shader
+	if err := s.shader.SetOnReload(s.setDepthProgram); err != nil {
+		s.shader.Delete()
+		return err
+	}
+	if s.data, err = glw.NewUniformBuffer(shadowBinding, shadowData{}); err != nil {
+		s.shader.Delete()
+		return err
+	}
+	if s.fb, err = newShadowFramebuffer(s.quality); err != nil {
+		s.shader.Delete()
+		s.data.Delete()
+		return err
+	}
+	return nil
+}
+
+//newShadowFramebuffer creates the layered depth framebuffer of the quality, it's nil when the shadows are off
+func newShadowFramebuffer(quality shadowQuality) (*glw.Framebuffer, error) {
+	if quality == shadowOff {
+		return nil, nil
+	}
+	settings := shadowSettings[quality]
+	return glw.NewFramebuffer(glw.FramebufferConfig{
+		Width:  settings.size,
+		Height: settings.size,
+		Layers: settings.cascades,
+		Depth:  &glw.ShadowAttachment,
+	})
+}
+
+//setDepthProgram sets the program rendering the shadow map
+func (s *shadowMap) setDepthProgram(p glw.Program) error {
+	s.p = p
+	return p.SetSampler("tex", 0)
+}
+
+//setDefines sets the defines of the shadow code in assets/shaders/shadow.glsl
+func (s *shadowMap) setDefines(pp *glw.Preprocessor) {
+	if s.quality == shadowOff {
+		pp.Undefine("SHADOWS")
+		pp.Undefine("SHADOW_CASCADES")
+		pp.Undefine("SHADOW_PCF")
+		return
+	}
+	settings := shadowSettings[s.quality]
+	pp.SetDefine("SHADOWS", "")
+	pp.SetDefine("SHADOW_CASCADES", strconv.Itoa(settings.cascades))
+	pp.SetDefine("SHADOW_PCF", strconv.Itoa(settings.pcf))
+}
+
+//SetQuality recreates the shadow map and recompiles the world program with the defines of the quality,
+//the previous shadow map, quality and program are kept if it fails
+func (s *shadowMap) SetQuality(quality shadowQuality) error {
+	fb, err := newShadowFramebuffer(quality)
+	if err != nil {
+		return err
+	}
+	oldQuality, oldFb := s.quality, s.fb
+	s.quality, s.fb = quality, fb
+	s.setDefines(s.w.pp)
+	if err := s.w.shader.Reload(); err != nil {
+		if fb != nil {
+			fb.Delete()
+		}
+		s.quality, s.fb = oldQuality, oldFb
+		s.setDefines(s.w.pp)
+		//the program may be already replaced when only its OnReload failed
+		if err := s.w.shader.Reload(); err != nil {
+			log.Println(err)
+		}
+		return err
+	}
+	if oldFb != nil {
+		oldFb.Delete()
+	}
+	return nil
+}
+
+//setProgram binds the shadow map and the uniform block to the program using the shadows
+func (s *shadowMap) setProgram(p glw.Program) error {
+	if s.quality == shadowOff {
+		return nil
+	}
+	if err := p.BindUniformBlock("Shadows", s.data); err != nil {
+		return err
+	}
+	return p.SetSampler("shadowMap", shadowUnit)
+}
+
+//bindTexture binds the shadow map to its texture unit
+func (s *shadowMap) bindTexture() {
+	if s.fb != nil {
+		s.fb.DepthTexture().BindArrayToUnit(shadowUnit)
+	}
+}
+
+func (s *shadowMap) update() {
+	s.shader.Poll()
+}
+
+func (s *shadowMap) delete() {
+	if s.fb != nil {
+		s.fb.Delete()
+	}
+	s.data.Delete()
+	s.shader.Delete()
+}
+
+//render draws the cascades and uploads their matrices,
+//the framebuffer and the viewport bound before are restored
+func (s *shadowMap) render() {
+	sunDir := sunDirection(s.w.dayTime)
+	//the shaders ignore the shadows when the sun is under the horizon
+	if s.fb == nil || sunDir[1] <= 0 {
+		return
+	}
+	var framebuffer int32
+	var viewport [4]int32
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &framebuffer)
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+
+	settings := shadowSettings[s.quality]
+	cam := s.w.cam
+	splits := cam.CascadeSplits(settings.cascades, shadowDistance, shadowSplitLambda)
+	data := shadowData{ViewDir: cam.Rotation.Sub(cam.Pos).Normalize()}
+	//maps the clip space of the light to the texture coordinates and the depth range
+	toTexture := mgl32.Translate3D(0.5, 0.5, 0.5).Mul4(mgl32.Scale3D(0.5, 0.5, 0.5))
+
+	s.p.UseProgram()
+	s.w.texture.BindToUnit(0)
+	gl.Enable(gl.POLYGON_OFFSET_FILL)
+	gl.PolygonOffset(2, 4)
+	for i := 0; i < settings.cascades; i++ {
+		m, texel := cascadeMatrix(cam, sunDir, splits[i], splits[i+1], settings.size)
+		data.Matrices[i] = toTexture.Mul4(m)
+		data.Splits[i] = splits[i+1]
+		data.Bias[i] = 1.5 * texel
+
+		s.fb.BindLayer(i)
+		gl.Clear(gl.DEPTH_BUFFER_BIT)
+		if err := s.p.SetMat4("lightProjView", m); err != nil {
+			log.Println(err)
+		}
+		frustum := glw.NewFrustum(m)
+		if err := s.p.SetFloat("alphaTest", 0); err != nil {
+			log.Println(err)
+		}
+		s.casters = s.w.shadowCasters(frustum, opaquePass, s.casters[:0])
+		s.w.arena.Draw(gl.TRIANGLES, s.casters)
+		//both sides of the cutout faces cast the shadows, like they are drawn
+		gl.Disable(gl.CULL_FACE)
+		if err := s.p.SetFloat("alphaTest", cutoutAlphaTest); err != nil {
+			log.Println(err)
+		}
+		s.casters = s.w.shadowCasters(frustum, cutoutPass, s.casters[:0])
+		s.w.arena.Draw(gl.TRIANGLES, s.casters)
+		gl.Enable(gl.CULL_FACE)
+	}
+	gl.Disable(gl.POLYGON_OFFSET_FILL)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(framebuffer))
+	gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+
+	if err := s.data.Set(data); err != nil {
+		log.Println(err)
+	}
+}
+
+//cascadeMatrix returns the orthographic projection of the sun covering the bounding sphere of the view frustum
+//between the near and far distances and the size of its texel in the world
+func cascadeMatrix(cam *glw.Camera, sunDir mgl32.Vec3, near, far float32, size int) (mgl32.Mat4, float32) {
+	corners := cam.FrustumCorners(near, far)
+	var center mgl32.Vec3
+	for _, c := range corners {
+		center = center.Add(c)
+	}
+	center = center.Mul(1.0 / float32(len(corners)))
+	var radius float32
+	for _, c := range corners {
+		if d := c.Sub(center).Len(); d > radius {
+			radius = d
+		}
+	}
+	//the sphere keeps the size of the projection when the camera rotates
+	radius = float32(math.Ceil(float64(radius)))
+	up := mgl32.Vec3{0, 1, 0}
+	if math.Abs(float64(sunDir[1])) > 0.99 {
+		up = mgl32.Vec3{0, 0, 1}
+	}
+	eye := center.Add(sunDir.Mul(radius + shadowCasterDistance))
+	view := mgl32.LookAtV(eye, center, up)
+	proj := mgl32.Ortho(-radius, radius, -radius, radius, 0, 2*radius+shadowCasterDistance)
+	m := proj.Mul4(view)
+
+	//snapping the origin to the texels keeps the shadow edges from shimmering when the camera moves
+	half := float32(size) / 2
+	origin := m.Mul4x1(mgl32.Vec4{0, 0, 0, 1})
+	m[12] += (float32(math.Round(float64(origin[0]*half))) - origin[0]*half) / half
+	m[13] += (float32(math.Round(float64(origin[1]*half))) - origin[1]*half) / half
+	return m, 2 * radius / float32(size)
+}
+
+//shadowCasters appends the meshes of the pass of the full detail sections in the light frustum,
+//the translucent blocks don't cast the shadows
+func (w *world) shadowCasters(frustum glw.Frustum, pass renderPass, meshes []*glw.ArenaMesh) []*glw.ArenaMesh {
+	p, q := w.player.Chunk()
+	for _, chunk := range w.chunks {
+		if abs(chunk.P-p) > chunkRenderRadius || abs(chunk.Q-q) > chunkRenderRadius || !w.fullDetail(chunk.P, chunk.Q) {
+			continue
+		}
+		for s := range chunk.sections {
+			if !chunk.hasMesh(s) || !frustum.ContainsAABB(chunk.sectionBounds(s)) {
+				continue
+			}
+			if mesh := chunk.sections[s][pass]; mesh != nil {
+				meshes = append(meshes, mesh)
+			}
+		}
+	}
+	return meshes
+}
+
+//registerShadowCommands adds the shadows command changing the shadow quality
+func registerShadowCommands(c *console, w *world) {
+	c.Register("shadows", "shadows [off|low|high] - shows or changes the quality of the shadows", func(args []string) error {
+		if len(args) == 0 {
+			log.Println("shadows are", w.shadows.quality)
+			return nil
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments")
+		}
+		quality, err := parseShadowQuality(args[0])
+		if err != nil {
+			return err
+		}
+		return w.shadows.SetQuality(quality)
+	})
+}
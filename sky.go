@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/microo8/craft/glw"
+)
+
+//sky renders the sky dome with the sun, the moon and the stars behind the terrain,
+//the colours and the sun direction are in the per frame data
+type sky struct {
+	w *world
+
+	shader *glw.ShaderProgram
+	p      glw.Program
+	//vao is empty, the fullscreen triangle is generated from gl_VertexID
+	vao glw.VertexArray
+}
+
+func newSky(w *world) *sky {
+	return &sky{w: w}
+}
+
+//Init creates the program of the sky, the world must be initialized first
+func (s *sky) Init(app *glw.App) error {
+	shader, err := glw.NewShaderProgram(nil,
+		glw.ShaderFile{Type: gl.VERTEX_SHADER, Path: "sky_vertex_shader.glsl"},
+		glw.ShaderFile{Type: gl.FRAGMENT_SHADER, Path: "sky_fragment_shader.glsl"},
+	)
+	if err != nil {
+		return err
+	}
+	s.shader = shader
+	s.vao = glw.NewVertexArray()
+	if err := s.shader.SetOnReload(s.setProgram); err != nil {
+		s.Destroy()
+		return err
+	}
+	return nil
+}
+
+func (s *sky) setProgram(p glw.Program) error {
+	s.p = p
+	return p.BindUniformBlock("PerFrame", s.w.frame)
+}
+
+//Update reloads the shaders when they change
+func (s *sky) Update(dt float64) {
+	s.shader.Poll()
+}
+
+//Destroy deletes the program and the vertex array
+func (s *sky) Destroy() {
+	s.vao.Delete()
+	s.shader.Delete()
+}
+
+//Render draws the sky at the far plane where the terrain wasn't drawn
+func (s *sky) Render(projView mgl32.Mat4, alpha float64) {
+	s.p.UseProgram()
+	if err := s.p.SetMat4("invProjView", s.w.cam.ProjView.Inv()); err != nil {
+		log.Println(err)
+	}
+	gl.DepthFunc(gl.LEQUAL)
+	gl.DepthMask(false)
+	s.vao.BindVertexArray()
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gl.DepthMask(true)
+	gl.DepthFunc(gl.LESS)
+}
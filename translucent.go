@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"sort"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/microo8/craft/glw"
+)
+
+//translucentSection is the translucent mesh of the visible section with its distance from the camera
+type translucentSection struct {
+	mesh *glw.ArenaMesh
+	dist float32
+}
+
+//translucentBlocks blends the translucent meshes of the sections found by the world over the scene,
+//it's rendered after the sky so the sky doesn't cover them
+type translucentBlocks struct {
+	w      *world
+	meshes []*glw.ArenaMesh
+}
+
+func newTranslucentBlocks(w *world) *translucentBlocks {
+	return &translucentBlocks{w: w}
+}
+
+//Render draws the sections back to front without writing the depth,
+//the faces inside of the section aren't sorted
+func (t *translucentBlocks) Render(projView mgl32.Mat4, alpha float64) {
+	w := t.w
+	if len(w.translucent) == 0 {
+		return
+	}
+	sort.Slice(w.translucent, func(i, j int) bool { return w.translucent[i].dist > w.translucent[j].dist })
+	t.meshes = t.meshes[:0]
+	for _, section := range w.translucent {
+		t.meshes = append(t.meshes, section.mesh)
+	}
+	w.p.UseProgram()
+	w.texture.BindToUnit(0)
+	w.chunkFade.BindToUnit(chunkFadeUnit)
+	w.shadows.bindTexture()
+	if err := w.p.SetFloat("alphaTest", 0); err != nil {
+		log.Println(err)
+	}
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.DepthMask(false)
+	//the water surface is seen from below too
+	gl.Disable(gl.CULL_FACE)
+	w.arena.DrawOrdered(gl.TRIANGLES, t.meshes)
+	gl.Enable(gl.CULL_FACE)
+	gl.DepthMask(true)
+	gl.Disable(gl.BLEND)
+}